@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/live"
+	"github.com/Hazealign/biosignal-hamilton-interface/metrics"
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+	"github.com/Hazealign/biosignal-hamilton-interface/rpc"
+)
+
+// 관리용 HTTP 서버의 경로들 (/metrics, /records, /live)
+var admin = http.NewServeMux()
+
+// --admin-address가 있으면 관리용 HTTP 서버를 띄웁니다.
+func ServeAdmin(address string) {
+	admin.Handle("/metrics", metrics.Default)
+
+	go func() {
+		if err := http.ListenAndServe(address, admin); err != nil {
+			log.Errorln("관리용 HTTP 서버를 열지 못했습니다.")
+			log.Errorln(err)
+		}
+	}()
+}
+
+// nsqd별 상태를 Metric으로 등록합니다.
+func RegisterBrokerMetrics(pool *mq.BrokerPool) {
+	var collect = func(value func(mq.BrokerHealth) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			var samples = []metrics.Sample{}
+			for _, health := range pool.Health() {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"address": health.Address},
+					Value:  value(health),
+				})
+			}
+
+			return samples
+		}
+	}
+
+	metrics.Default.Register(metrics.Metric{
+		Name: "signalize_nsq_broker_up",
+		Help: "Whether the nsqd is healthy (1) or failed (0)",
+		Type: metrics.GAUGE,
+		Collect: collect(func(health mq.BrokerHealth) float64 {
+			if health.Healthy {
+				return 1
+			}
+
+			return 0
+		}),
+	})
+
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_nsq_broker_published_total",
+		Help:    "Number of successful publishes to the nsqd",
+		Type:    metrics.COUNTER,
+		Collect: collect(func(health mq.BrokerHealth) float64 { return float64(health.Published) }),
+	})
+
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_nsq_broker_failures_total",
+		Help:    "Number of failed publishes and pings to the nsqd",
+		Type:    metrics.COUNTER,
+		Collect: collect(func(health mq.BrokerHealth) float64 { return float64(health.Failures) }),
+	})
+}
+
+// MPUB마다 걸린 시간과 메시지 수를 Metric으로 남기는 OnBatch
+func BatchMetrics() func(mq.BatchReport) {
+	var latency = metrics.NewHistogram(0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1)
+	var size = metrics.NewHistogram(1, 5, 10, 25, 50, 100, 250, 500, 1000)
+
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_nsq_batch_latency_seconds",
+		Help:    "Time taken by one MPUB",
+		Type:    metrics.HISTOGRAM,
+		Collect: func() []metrics.Sample { return latency.Samples(nil) },
+	})
+
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_nsq_batch_messages",
+		Help:    "Number of messages in one MPUB",
+		Type:    metrics.HISTOGRAM,
+		Collect: func() []metrics.Sample { return size.Samples(nil) },
+	})
+
+	return func(report mq.BatchReport) {
+		latency.Observe(report.Latency.Seconds())
+		size.Observe(float64(report.Count))
+		log.Debugf("%s 토픽에 %d개(%d byte)를 보냈습니다. 대기 %s, 전송 %s", report.Topic, report.Count, report.Bytes, report.Waited, report.Latency)
+	}
+}
+
+// WebSocket 클라이언트 수와 버린 메시지 수를 Metric으로 등록합니다.
+func RegisterLiveMetrics(hub *live.Hub) {
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_live_clients",
+		Help:    "Number of connected /live WebSocket clients",
+		Type:    metrics.GAUGE,
+		Collect: func() []metrics.Sample { return []metrics.Sample{{Value: float64(hub.Clients())}} },
+	})
+
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_live_dropped_total",
+		Help:    "Number of messages dropped for slow /live clients",
+		Type:    metrics.COUNTER,
+		Collect: func() []metrics.Sample { return []metrics.Sample{{Value: float64(hub.Dropped())}} },
+	})
+}
+
+func RegisterRPCMetrics(server *rpc.Server) {
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_grpc_streams",
+		Help:    "Number of open gRPC streams",
+		Type:    metrics.GAUGE,
+		Collect: func() []metrics.Sample { return []metrics.Sample{{Value: float64(server.Streams())}} },
+	})
+
+	metrics.Default.Register(metrics.Metric{
+		Name:    "signalize_grpc_dropped_total",
+		Help:    "Number of messages dropped for slow gRPC streams",
+		Type:    metrics.COUNTER,
+		Collect: func() []metrics.Sample { return []metrics.Sample{{Value: float64(server.Dropped())}} },
+	})
+}
@@ -0,0 +1,236 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ASYNC_INEFFECTIVE_EFFORT = "INEFFECTIVE_EFFORT"
+	ASYNC_DOUBLE_TRIGGER     = "DOUBLE_TRIGGER"
+	ASYNC_PREMATURE_CYCLING  = "PREMATURE_CYCLING"
+	ASYNC_LATE_CYCLING       = "LATE_CYCLING"
+)
+
+// 환자-벤틸레이터 비동기로 의심되는 이벤트
+type AsynchronyEvent struct {
+	Timestamp  time.Time
+	Kind       string
+	Confidence float64 // 0 ~ 1
+}
+
+// Window 동안의 비동기 지수 (이벤트 수 / (호흡 수 + 무효 노력 수) * 100)
+type AsynchronyIndex struct {
+	Start   time.Time
+	End     time.Time
+	Events  int
+	Breaths int
+	Index   float64
+}
+
+// Waveform 샘플과 호흡을 받아 비동기 이벤트를 찾습니다.
+//
+//   - 무효 노력: 호기 중 Flow가 EffortThreshold 이상 올라갔다가 그 절반 이상 다시 떨어졌지만 흡기가 시작되지 않음
+//   - 이중 유발: 호기 시간이 최근 흡기 시간 중앙값의 절반보다 짧음
+//   - 조기/지연 순환: 흡기 시간이 최근 흡기 시간 중앙값보다 CyclingRatio 이상 짧거나 김
+type AsynchronyDetector struct {
+	FlowThreshold   float64 // L/min, Segmenter와 같은 흡기/호기 판단 기준
+	EffortThreshold float64 // L/min
+	CyclingRatio    float64
+	History         int // 중앙값을 계산할 최근 호흡 수
+	Window          time.Duration
+	OnEvent         func(AsynchronyEvent)
+	OnIndex         func(AsynchronyIndex)
+
+	expiration  bool
+	trough      float64
+	troughPress float64
+	peak        *Sample
+	peakDip     bool
+
+	times       []time.Duration
+	windowStart time.Time
+	events      int
+	breaths     int
+	efforts     int
+}
+
+func NewAsynchronyDetector(onEvent func(AsynchronyEvent), onIndex func(AsynchronyIndex)) *AsynchronyDetector {
+	return &AsynchronyDetector{
+		FlowThreshold:   2,
+		EffortThreshold: 5,
+		CyclingRatio:    0.5,
+		History:         8,
+		Window:          time.Minute,
+		OnEvent:         onEvent,
+		OnIndex:         onIndex,
+	}
+}
+
+func (detector *AsynchronyDetector) emit(event AsynchronyEvent) {
+	detector.events++
+	if event.Kind == ASYNC_INEFFECTIVE_EFFORT {
+		detector.efforts++
+	}
+
+	if detector.OnEvent != nil {
+		detector.OnEvent(event)
+	}
+}
+
+func clamp(value float64) float64 {
+	if value < 0 {
+		return 0
+	} else if value > 1 {
+		return 1
+	}
+
+	return value
+}
+
+func (detector *AsynchronyDetector) Add(sample Sample) {
+	detector.rollWindow(sample.Timestamp)
+
+	if sample.Flow > detector.FlowThreshold {
+		detector.expiration = false
+		detector.peak = nil
+		return
+	}
+
+	if sample.Flow < -detector.FlowThreshold && !detector.expiration {
+		detector.expiration = true
+		detector.trough = sample.Flow
+		detector.troughPress = sample.Pressure
+		detector.peak = nil
+		return
+	}
+
+	if !detector.expiration {
+		return
+	}
+
+	if detector.peak == nil {
+		if sample.Flow < detector.trough {
+			detector.trough = sample.Flow
+			detector.troughPress = sample.Pressure
+		} else if sample.Flow-detector.trough >= detector.EffortThreshold {
+			var peak = sample
+			detector.peak = &peak
+			detector.peakDip = sample.Pressure < detector.troughPress
+		}
+
+		return
+	}
+
+	if sample.Flow > detector.peak.Flow {
+		var peak = sample
+		detector.peak = &peak
+		detector.peakDip = detector.peakDip || sample.Pressure < detector.troughPress
+		return
+	}
+
+	// 올라간 뒤 EffortThreshold의 절반 이상 다시 떨어지면 흡기로 이어지지 않은 노력으로 봅니다.
+	// 수동적인 호기에서는 Flow가 0을 향해 계속 올라가기만 합니다.
+	if fall := detector.peak.Flow - sample.Flow; fall >= detector.EffortThreshold/2 {
+		var confidence = fall / detector.EffortThreshold
+		if detector.peakDip {
+			confidence += 0.2
+		}
+
+		detector.emit(AsynchronyEvent{
+			Timestamp:  detector.peak.Timestamp,
+			Kind:       ASYNC_INEFFECTIVE_EFFORT,
+			Confidence: clamp(confidence),
+		})
+
+		detector.trough = sample.Flow
+		detector.troughPress = sample.Pressure
+		detector.peak = nil
+	}
+}
+
+func (detector *AsynchronyDetector) median() time.Duration {
+	var sorted = append([]time.Duration{}, detector.times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[len(sorted)/2]
+}
+
+func (detector *AsynchronyDetector) AddBreath(breath Breath) {
+	detector.rollWindow(breath.End)
+	detector.breaths++
+
+	// 기준이 될 호흡이 충분히 쌓인 뒤부터 판단합니다.
+	if len(detector.times) >= detector.History/2 && breath.InspiratoryTime > 0 {
+		var median = detector.median().Seconds()
+		var ti = breath.InspiratoryTime.Seconds()
+		var te = breath.ExpiratoryTime.Seconds()
+
+		if te < median/2 {
+			detector.emit(AsynchronyEvent{
+				Timestamp:  breath.End,
+				Kind:       ASYNC_DOUBLE_TRIGGER,
+				Confidence: clamp(1 - te/(median/2)),
+			})
+		}
+
+		if ti < median*(1-detector.CyclingRatio) {
+			detector.emit(AsynchronyEvent{
+				Timestamp:  breath.Start.Add(breath.InspiratoryTime),
+				Kind:       ASYNC_PREMATURE_CYCLING,
+				Confidence: clamp(1 - ti/(median*(1-detector.CyclingRatio))),
+			})
+		} else if ti > median*(1+detector.CyclingRatio) {
+			detector.emit(AsynchronyEvent{
+				Timestamp:  breath.Start.Add(breath.InspiratoryTime),
+				Kind:       ASYNC_LATE_CYCLING,
+				Confidence: clamp(ti/(median*(1+detector.CyclingRatio)) - 1),
+			})
+		}
+	}
+
+	detector.times = append(detector.times, breath.InspiratoryTime)
+	if len(detector.times) > detector.History {
+		detector.times = detector.times[1:]
+	}
+}
+
+// Window가 지나면 비동기 지수를 계산하고 새 Window를 시작합니다.
+func (detector *AsynchronyDetector) rollWindow(now time.Time) {
+	if detector.windowStart.IsZero() {
+		detector.windowStart = now
+		return
+	}
+
+	if now.Sub(detector.windowStart) < detector.Window {
+		return
+	}
+
+	var index = AsynchronyIndex{
+		Start:   detector.windowStart,
+		End:     now,
+		Events:  detector.events,
+		Breaths: detector.breaths,
+	}
+
+	if total := detector.breaths + detector.efforts; total > 0 {
+		index.Index = float64(detector.events) / float64(total) * 100
+	}
+
+	if detector.OnIndex != nil {
+		detector.OnIndex(index)
+	}
+
+	detector.windowStart = now
+	detector.events = 0
+	detector.breaths = 0
+	detector.efforts = 0
+}
+
+func (index AsynchronyIndex) Sources() []string {
+	return []string{
+		fmt.Sprintf("Breath x%d", index.Breaths),
+		fmt.Sprintf("Asynchrony Event x%d", index.Events),
+	}
+}
@@ -0,0 +1,257 @@
+package analysis
+
+import (
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+)
+
+// C_120 스트림에서 받은 하나의 샘플 (물리 단위)
+type Sample struct {
+	Timestamp time.Time
+	Pressure  float64 // P_PATIENT, cmH2O
+	Flow      float64 // FLOW, L/min (흡기가 +)
+	Volume    float64 // VOLUME, mL
+	Status    byte
+}
+
+func SampleFrom(timestamp time.Time, online packet.OnlineValues120) Sample {
+	var sample = Sample{
+		Timestamp: timestamp,
+		Status:    online.VentilatorStatus,
+	}
+
+	for _, channel := range online.Samples() {
+		switch channel.Key {
+		case "P_PATIENT":
+			sample.Pressure = channel.Value()
+		case "FLOW":
+			sample.Flow = channel.Value()
+		case "VOLUME":
+			sample.Volume = channel.Value()
+		}
+	}
+
+	return sample
+}
+
+type Phase int
+
+const (
+	PHASE_UNKNOWN Phase = iota
+	PHASE_INSPIRATION
+	PHASE_EXPIRATION
+)
+
+// 한 호흡(흡기 시작 ~ 다음 흡기 시작)에서 계산한 값
+type Breath struct {
+	Start           time.Time
+	End             time.Time
+	InspiratoryTime time.Duration
+	ExpiratoryTime  time.Duration
+	VTi             float64 // mL
+	VTe             float64 // mL
+	PeakPressure    float64 // cmH2O
+	PlateauPressure float64 // cmH2O, HasPlateau가 false면 의미없음
+	HasPlateau      bool
+	PEEP            float64 // cmH2O
+	IERatio         float64 // 1:x 에서 x (Te / Ti)
+	Spontaneous     bool
+}
+
+// NSQ에 보낼 때 사용하는 Key, 값, 단위
+type Metric struct {
+	Key   string
+	Value float64
+	Unit  string
+}
+
+func (breath Breath) Metrics() []Metric {
+	var spontaneous = float64(0)
+	if breath.Spontaneous {
+		spontaneous = 1
+	}
+
+	var metrics = []Metric{
+		{Key: "VTI", Value: breath.VTi, Unit: "mL"},
+		{Key: "VTE", Value: breath.VTe, Unit: "mL"},
+		{Key: "P_PEAK", Value: breath.PeakPressure, Unit: "cmH2O"},
+		{Key: "PEEP", Value: breath.PEEP, Unit: "cmH2O"},
+		{Key: "I_E", Value: breath.IERatio, Unit: ""},
+		{Key: "T_INSP", Value: breath.InspiratoryTime.Seconds(), Unit: "s"},
+		{Key: "SPONTANEOUS", Value: spontaneous, Unit: ""},
+	}
+
+	if breath.HasPlateau {
+		metrics = append(metrics, Metric{Key: "P_PLATEAU", Value: breath.PlateauPressure, Unit: "cmH2O"})
+	}
+
+	return metrics
+}
+
+// Waveform 샘플을 받아 흡기/호기 경계를 찾고, 호흡이 끝날 때마다 OnBreath를 호출합니다.
+// 기본적으로 Flow의 부호로 경계를 찾으며, PhaseFromStatus가 있으면 VentilatorStatus를 먼저 사용합니다.
+type Segmenter struct {
+	FlowThreshold    float64 // L/min, 이 값을 넘어야 흡기/호기로 판단
+	TriggerThreshold float64 // cmH2O, 흡기 직전 압력이 PEEP보다 이만큼 떨어지면 자발 호흡
+	PhaseFromStatus  func(status byte) Phase
+	OnBreath         func(Breath)
+
+	phase    Phase
+	last     *Sample
+	current  Breath
+	expStart time.Time
+	peakFlow float64
+	pause    []float64 // 흡기 중 Flow가 멈춘 구간의 압력
+	minStart float64   // 흡기 직전 ~ 최대 Flow까지의 최저 압력
+	endExp   float64   // 마지막으로 Flow가 나가고 있던 샘플의 압력
+	prevPEEP float64
+	hasPEEP  bool
+}
+
+// VentilatorStatus의 흡기/호기 비트로 호흡 단계를 판단합니다. 둘 다 0이거나 둘 다 1이면 PHASE_UNKNOWN이므로 Flow로 판단합니다.
+func PhaseFromVentilatorStatus(status byte) Phase {
+	var inspiration = status&packet.STATUS_INSPIRATION != 0
+	var expiration = status&packet.STATUS_EXPIRATION != 0
+
+	switch {
+	case inspiration && !expiration:
+		return PHASE_INSPIRATION
+	case expiration && !inspiration:
+		return PHASE_EXPIRATION
+	}
+
+	return PHASE_UNKNOWN
+}
+
+func NewSegmenter(onBreath func(Breath)) *Segmenter {
+	return &Segmenter{
+		FlowThreshold:    2,
+		TriggerThreshold: 0.5,
+		OnBreath:         onBreath,
+	}
+}
+
+func (segmenter *Segmenter) Add(sample Sample) {
+	var next = segmenter.detectPhase(sample)
+
+	switch {
+	case next == PHASE_INSPIRATION && segmenter.phase != PHASE_INSPIRATION:
+		segmenter.startInspiration(sample)
+	case next == PHASE_EXPIRATION && segmenter.phase == PHASE_INSPIRATION:
+		segmenter.expStart = sample.Timestamp
+		segmenter.current.InspiratoryTime = sample.Timestamp.Sub(segmenter.current.Start)
+	}
+
+	if next != PHASE_UNKNOWN {
+		segmenter.phase = next
+	}
+
+	if next == PHASE_EXPIRATION {
+		segmenter.endExp = sample.Pressure
+	}
+
+	if segmenter.last != nil {
+		segmenter.integrate(*segmenter.last, sample)
+	}
+
+	segmenter.track(sample)
+	segmenter.last = &sample
+}
+
+func (segmenter *Segmenter) detectPhase(sample Sample) Phase {
+	if segmenter.PhaseFromStatus != nil {
+		if phase := segmenter.PhaseFromStatus(sample.Status); phase != PHASE_UNKNOWN {
+			return phase
+		}
+	}
+
+	if sample.Flow > segmenter.FlowThreshold {
+		return PHASE_INSPIRATION
+	} else if sample.Flow < -segmenter.FlowThreshold {
+		return PHASE_EXPIRATION
+	}
+
+	return PHASE_UNKNOWN
+}
+
+// 사다리꼴 적분으로 Flow(L/min)를 Volume(mL)으로 바꿔 더합니다.
+// 들어간 양은 VTi, 나온 양은 VTe에 더합니다.
+func (segmenter *Segmenter) integrate(previous Sample, sample Sample) {
+	var seconds = sample.Timestamp.Sub(previous.Timestamp).Seconds()
+	var volume = (previous.Flow + sample.Flow) / 2 * seconds / 60 * 1000
+
+	if volume > 0 {
+		segmenter.current.VTi += volume
+	} else {
+		segmenter.current.VTe -= volume
+	}
+}
+
+func (segmenter *Segmenter) startInspiration(sample Sample) {
+	var previous = segmenter.current
+
+	// 첫 흡기 이전의 샘플로는 호흡을 만들지 않습니다.
+	if !previous.Start.IsZero() && !segmenter.expStart.IsZero() {
+		previous.End = sample.Timestamp
+		previous.ExpiratoryTime = sample.Timestamp.Sub(segmenter.expStart)
+		if previous.InspiratoryTime > 0 {
+			previous.IERatio = previous.ExpiratoryTime.Seconds() / previous.InspiratoryTime.Seconds()
+		}
+
+		// 흡기를 유발하려고 압력이 떨어진 구간은 빼고, Flow가 나가던 마지막 압력을 PEEP로 봅니다.
+		previous.PEEP = segmenter.endExp
+
+		if len(segmenter.pause) > 0 {
+			var sum = float64(0)
+			for _, pressure := range segmenter.pause {
+				sum += pressure
+			}
+
+			previous.PlateauPressure = sum / float64(len(segmenter.pause))
+			previous.HasPlateau = true
+		}
+
+		segmenter.prevPEEP = previous.PEEP
+		segmenter.hasPEEP = true
+
+		if segmenter.OnBreath != nil {
+			segmenter.OnBreath(previous)
+		}
+	}
+
+	segmenter.current = Breath{
+		Start:        sample.Timestamp,
+		PeakPressure: sample.Pressure,
+	}
+	segmenter.expStart = time.Time{}
+	segmenter.peakFlow = 0
+	segmenter.pause = nil
+	segmenter.minStart = sample.Pressure
+	if segmenter.last != nil && segmenter.last.Pressure < segmenter.minStart {
+		segmenter.minStart = segmenter.last.Pressure
+	}
+}
+
+func (segmenter *Segmenter) track(sample Sample) {
+	if segmenter.phase != PHASE_INSPIRATION || !segmenter.expStart.IsZero() {
+		return
+	}
+
+	if sample.Pressure > segmenter.current.PeakPressure {
+		segmenter.current.PeakPressure = sample.Pressure
+	}
+
+	if sample.Flow > segmenter.peakFlow {
+		segmenter.peakFlow = sample.Flow
+		if sample.Pressure < segmenter.minStart {
+			segmenter.minStart = sample.Pressure
+		}
+	} else if sample.Flow < segmenter.FlowThreshold && sample.Flow > -segmenter.FlowThreshold {
+		segmenter.pause = append(segmenter.pause, sample.Pressure)
+	}
+
+	// 흡기 시작 부근에서 압력이 PEEP 아래로 떨어졌다면 환자가 호흡을 유발한 것으로 봅니다.
+	segmenter.current.Spontaneous = segmenter.hasPEEP &&
+		segmenter.minStart < segmenter.prevPEEP-segmenter.TriggerThreshold
+}
@@ -0,0 +1,186 @@
+package analysis
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+)
+
+// 여러 값을 조합해서 계산한 지표
+// Sources에는 계산에 사용한 값들이 들어갑니다. (ex. "63 f total")
+type Indicator struct {
+	Timestamp time.Time
+	Key       string
+	Value     float64
+	Unit      string
+	Sources   []string
+}
+
+type numericValue struct {
+	value     float64
+	timestamp time.Time
+}
+
+// 폴링한 Numeric 값과 호흡 단위의 값으로 이탈(Weaning) 지표를 계산합니다.
+//
+//   - RSBI: f total(63) / VT Insp spont(77, L)
+//   - SPONT_RATIO: f spont(64) / f total(63) * 100
+//   - P01_TREND: Window 동안 P01(115)의 기울기 (cmH2O/min)
+//   - RSBI_WAVEFORM, SPONT_RATIO_WAVEFORM: Window 동안의 Breath로 계산한 같은 값
+type DerivedEngine struct {
+	MaxAge      time.Duration // 이보다 오래된 Numeric 값은 사용하지 않음
+	Window      time.Duration
+	OnIndicator func(Indicator)
+
+	numerics map[int]numericValue
+	p01      []numericValue
+	breaths  []Breath
+}
+
+func NewDerivedEngine(onIndicator func(Indicator)) *DerivedEngine {
+	return &DerivedEngine{
+		MaxAge:      2 * time.Minute,
+		Window:      time.Minute,
+		OnIndicator: onIndicator,
+		numerics:    map[int]numericValue{},
+	}
+}
+
+func source(identifier int) string {
+	return fmt.Sprintf("%d %s", identifier, packet.TypeIntString[identifier])
+}
+
+func (engine *DerivedEngine) emit(indicator Indicator) {
+	if engine.OnIndicator != nil {
+		engine.OnIndicator(indicator)
+	}
+}
+
+// 기준 시각에서 MaxAge 이내에 들어온 값만 반환합니다.
+func (engine *DerivedEngine) fresh(identifier int, now time.Time) (float64, bool) {
+	numeric, ok := engine.numerics[identifier]
+	if !ok || now.Sub(numeric.timestamp) > engine.MaxAge {
+		return 0, false
+	}
+
+	return numeric.value, true
+}
+
+func (engine *DerivedEngine) AddNumeric(identifier int, value float64, timestamp time.Time) {
+	engine.numerics[identifier] = numericValue{value: value, timestamp: timestamp}
+
+	if identifier == 63 || identifier == 77 {
+		total, ok1 := engine.fresh(63, timestamp)
+		volume, ok2 := engine.fresh(77, timestamp)
+		if ok1 && ok2 && volume > 0 {
+			engine.emit(Indicator{
+				Timestamp: timestamp,
+				Key:       "RSBI",
+				Value:     total / (volume / 1000),
+				Unit:      "b/min/L",
+				Sources:   []string{source(63), source(77)},
+			})
+		}
+	}
+
+	if identifier == 63 || identifier == 64 {
+		total, ok1 := engine.fresh(63, timestamp)
+		spont, ok2 := engine.fresh(64, timestamp)
+		if ok1 && ok2 && total > 0 {
+			engine.emit(Indicator{
+				Timestamp: timestamp,
+				Key:       "SPONT_RATIO",
+				Value:     spont / total * 100,
+				Unit:      "%",
+				Sources:   []string{source(64), source(63)},
+			})
+		}
+	}
+
+	if identifier == 115 {
+		engine.p01 = append(engine.p01, numericValue{value: value, timestamp: timestamp})
+		for len(engine.p01) > 0 && timestamp.Sub(engine.p01[0].timestamp) > engine.Window {
+			engine.p01 = engine.p01[1:]
+		}
+
+		if slope, ok := trend(engine.p01); ok {
+			engine.emit(Indicator{
+				Timestamp: timestamp,
+				Key:       "P01_TREND",
+				Value:     slope,
+				Unit:      "cmH2O/min",
+				Sources:   []string{fmt.Sprintf("%s x%d", source(115), len(engine.p01))},
+			})
+		}
+	}
+}
+
+// 최소제곱법으로 구한 분당 기울기 (값이 3개 이상일 때만)
+func trend(values []numericValue) (float64, bool) {
+	if len(values) < 3 {
+		return 0, false
+	}
+
+	var n = float64(len(values))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, value := range values {
+		var x = value.timestamp.Sub(values[0].timestamp).Minutes()
+		sumX += x
+		sumY += value.value
+		sumXY += x * value.value
+		sumXX += x * x
+	}
+
+	var denominator = n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+func (engine *DerivedEngine) AddBreath(breath Breath) {
+	engine.breaths = append(engine.breaths, breath)
+	for len(engine.breaths) > 0 && breath.End.Sub(engine.breaths[0].End) > engine.Window {
+		engine.breaths = engine.breaths[1:]
+	}
+
+	// 한 호흡 이상의 시간이 쌓여야 호흡수를 계산할 수 있습니다.
+	var span = breath.End.Sub(engine.breaths[0].Start)
+	if len(engine.breaths) < 2 || span <= 0 {
+		return
+	}
+
+	var spontaneous = 0
+	var volume = float64(0)
+	for _, breath := range engine.breaths {
+		if breath.Spontaneous {
+			spontaneous++
+		}
+
+		volume += breath.VTe
+	}
+
+	var count = float64(len(engine.breaths))
+	var rate = count / span.Minutes()
+	var sources = []string{fmt.Sprintf("Breath x%d (%s)", len(engine.breaths), span)}
+
+	if volume > 0 {
+		engine.emit(Indicator{
+			Timestamp: breath.End,
+			Key:       "RSBI_WAVEFORM",
+			Value:     rate / (volume / count / 1000),
+			Unit:      "b/min/L",
+			Sources:   sources,
+		})
+	}
+
+	engine.emit(Indicator{
+		Timestamp: breath.End,
+		Key:       "SPONT_RATIO_WAVEFORM",
+		Value:     float64(spontaneous) / count * 100,
+		Unit:      "%",
+		Sources:   sources,
+	})
+}
@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+)
+
+const (
+	QUALITY_GOOD      = "GOOD"
+	QUALITY_FLATLINE  = "FLATLINE"  // 같은 값이 FlatlineDuration 이상 계속됨
+	QUALITY_SATURATED = "SATURATED" // 12bit의 최소(0) 또는 최대(4095) 값
+	QUALITY_JUMP      = "JUMP"      // 직전 샘플과 MaxJump 이상 차이남
+	QUALITY_MISSING   = "MISSING"   // 직전 샘플과 MaxGap 이상 떨어져 있음
+	QUALITY_INACTIVE  = "INACTIVE"  // 값이 한 번도 바뀌지 않은 채널 (ex. 연결되지 않은 P_OPTIONAL)
+)
+
+// 채널의 품질이 바뀌었을 때 발생하는 이벤트
+type QualityEvent struct {
+	Timestamp time.Time
+	Key       string
+	From      string
+	To        string
+}
+
+func (event QualityEvent) Degraded() bool {
+	return event.To != QUALITY_GOOD
+}
+
+type channelState struct {
+	first     uint16
+	count     uint16
+	timestamp time.Time
+	sameSince time.Time
+	period    time.Duration // 측정한 샘플 간격
+	active    bool
+	quality   string
+	pending   string // 바뀌려는 품질
+	streak    int    // pending이 연속으로 나온 샘플 수
+}
+
+// Waveform 채널별로 센서가 빠지거나 값이 고정된 상태를 찾습니다.
+// 응답 하나가 늦거나 빠져서 품질이 오가지 않도록 같은 판단이 Hysteresis번 연속되어야 품질을 바꿉니다.
+type SignalQuality struct {
+	FlatlineDuration time.Duration
+	MaxJump          int
+	MaxGap           time.Duration // 0이면 측정한 샘플 간격 * GapFactor
+	GapFactor        float64
+	Hysteresis       int
+	OnChange         func(QualityEvent)
+
+	channels map[string]*channelState
+}
+
+func NewSignalQuality(onChange func(QualityEvent)) *SignalQuality {
+	return &SignalQuality{
+		FlatlineDuration: 5 * time.Second,
+		MaxJump:          1024,
+		// 늦은 응답은 packet.MaxStaleReplies번까지 다시 읽으므로 그만큼 늦어져도 MISSING으로 보지 않습니다.
+		GapFactor:  packet.MaxStaleReplies + 2,
+		Hysteresis: 3,
+		OnChange:   onChange,
+		channels:   map[string]*channelState{},
+	}
+}
+
+func (monitor *SignalQuality) maxGap(state *channelState) time.Duration {
+	if monitor.MaxGap > 0 {
+		return monitor.MaxGap
+	}
+
+	return time.Duration(float64(state.period) * monitor.GapFactor)
+}
+
+// 샘플의 품질을 판단해서 채널의 품질을 반환하고, 채널의 품질이 바뀌었다면 OnChange를 호출합니다.
+// 값이 한 번도 바뀌지 않은 채널은 QUALITY_INACTIVE이며 OnChange를 호출하지 않습니다.
+func (monitor *SignalQuality) Check(timestamp time.Time, sample packet.ChannelSample) string {
+	var state, ok = monitor.channels[sample.Key]
+	if !ok {
+		state = &channelState{first: sample.Count, count: sample.Count, timestamp: timestamp, sameSince: timestamp, quality: QUALITY_INACTIVE}
+		monitor.channels[sample.Key] = state
+	}
+
+	var jump = int(sample.Count) - int(state.count)
+	if jump < 0 {
+		jump = -jump
+	}
+
+	if sample.Count != state.count {
+		state.sameSince = timestamp
+	}
+
+	var gap = timestamp.Sub(state.timestamp)
+	var maxGap = monitor.maxGap(state)
+
+	var quality = QUALITY_GOOD
+	switch {
+	case sample.Count == 0 || sample.Count == 0x0FFF:
+		quality = QUALITY_SATURATED
+	case ok && maxGap > 0 && gap > maxGap:
+		quality = QUALITY_MISSING
+	case ok && jump > monitor.MaxJump:
+		quality = QUALITY_JUMP
+	case timestamp.Sub(state.sameSince) >= monitor.FlatlineDuration:
+		quality = QUALITY_FLATLINE
+	}
+
+	// 끊긴 구간은 샘플 간격에 넣지 않습니다.
+	if gap > 0 && quality != QUALITY_MISSING {
+		if state.period == 0 {
+			state.period = gap
+		} else {
+			state.period += (gap - state.period) / 8
+		}
+	}
+
+	state.count = sample.Count
+	state.timestamp = timestamp
+
+	if !state.active {
+		if sample.Count == state.first {
+			return QUALITY_INACTIVE
+		}
+
+		state.active = true
+		state.quality = QUALITY_GOOD
+	}
+
+	monitor.settle(state, timestamp, sample.Key, quality)
+	return state.quality
+}
+
+// quality가 Hysteresis번 연속되면 채널의 품질을 바꿉니다.
+func (monitor *SignalQuality) settle(state *channelState, timestamp time.Time, key string, quality string) {
+	if quality == state.quality {
+		state.pending = ""
+		state.streak = 0
+		return
+	}
+
+	if quality != state.pending {
+		state.pending = quality
+		state.streak = 0
+	}
+
+	state.streak++
+	if state.streak < monitor.Hysteresis {
+		return
+	}
+
+	if monitor.OnChange != nil {
+		monitor.OnChange(QualityEvent{
+			Timestamp: timestamp,
+			Key:       key,
+			From:      state.quality,
+			To:        quality,
+		})
+	}
+
+	state.quality = quality
+	state.pending = ""
+	state.streak = 0
+}
@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+)
+
+// 하나의 Numeric 값을 Interval 동안 모은 요약
+// 유효한 값이 없는 경우(Count == 0)에는 Min ~ Last가 의미없습니다.
+type TrendSummary struct {
+	Start      time.Time
+	End        time.Time
+	Interval   time.Duration
+	Identifier int
+	Key        string
+	Unit       string
+	Min        float64
+	Max        float64
+	Mean       float64
+	Median     float64
+	Last       float64
+	Count      int
+	Invalid    int // 읽을 수 없거나 측정되지 않은 값의 수
+}
+
+type trendWindow struct {
+	start   time.Time
+	values  []float64
+	last    float64
+	invalid int
+}
+
+// 폴링한 Numeric 값을 Interval마다 모아서 요약합니다.
+// Window는 Interval 단위로 정렬되며(ex. 1분이면 매 분 0초), 다음 Window의 값이 들어오거나 Flush를 호출하면 OnSummary를 호출합니다.
+type TrendAggregator struct {
+	Intervals []time.Duration
+	OnSummary func(TrendSummary)
+
+	windows map[time.Duration]map[int]*trendWindow
+}
+
+func NewTrendAggregator(intervals []time.Duration, onSummary func(TrendSummary)) *TrendAggregator {
+	var aggregator = &TrendAggregator{
+		OnSummary: onSummary,
+		windows:   map[time.Duration]map[int]*trendWindow{},
+	}
+
+	for _, interval := range intervals {
+		if interval > 0 {
+			aggregator.Intervals = append(aggregator.Intervals, interval)
+			aggregator.windows[interval] = map[int]*trendWindow{}
+		}
+	}
+
+	return aggregator
+}
+
+// valid가 false이거나 값이 NaN, Inf인 경우에는 Invalid로만 셉니다.
+func (aggregator *TrendAggregator) Add(identifier int, value float64, valid bool, timestamp time.Time) {
+	aggregator.Flush(timestamp)
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		valid = false
+	}
+
+	for _, interval := range aggregator.Intervals {
+		var windows = aggregator.windows[interval]
+		window, ok := windows[identifier]
+		if !ok {
+			window = &trendWindow{start: timestamp.Truncate(interval)}
+			windows[identifier] = window
+		}
+
+		if valid {
+			window.values = append(window.values, value)
+			window.last = value
+		} else {
+			window.invalid++
+		}
+	}
+}
+
+// now 이전에 끝난 Window들을 요약해서 OnSummary를 호출합니다.
+func (aggregator *TrendAggregator) Flush(now time.Time) {
+	for _, interval := range aggregator.Intervals {
+		var windows = aggregator.windows[interval]
+
+		var identifiers = []int{}
+		for identifier, window := range windows {
+			if !now.Before(window.start.Add(interval)) {
+				identifiers = append(identifiers, identifier)
+			}
+		}
+
+		sort.Ints(identifiers)
+		for _, identifier := range identifiers {
+			var summary = windows[identifier].summarize(identifier, interval)
+			delete(windows, identifier)
+
+			if aggregator.OnSummary != nil {
+				aggregator.OnSummary(summary)
+			}
+		}
+	}
+}
+
+func (window *trendWindow) summarize(identifier int, interval time.Duration) TrendSummary {
+	var summary = TrendSummary{
+		Start:      window.start,
+		End:        window.start.Add(interval),
+		Interval:   interval,
+		Identifier: identifier,
+		Key:        packet.TypeIntString[identifier],
+		Unit:       packet.TypeUnit[identifier],
+		Count:      len(window.values),
+		Invalid:    window.invalid,
+	}
+
+	if len(window.values) == 0 {
+		return summary
+	}
+
+	var sorted = append([]float64{}, window.values...)
+	sort.Float64s(sorted)
+
+	var sum = float64(0)
+	for _, value := range sorted {
+		sum += value
+	}
+
+	summary.Min = sorted[0]
+	summary.Max = sorted[len(sorted)-1]
+	summary.Mean = sum / float64(len(sorted))
+	summary.Last = window.last
+
+	if len(sorted)%2 == 1 {
+		summary.Median = sorted[len(sorted)/2]
+	} else {
+		summary.Median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	return summary
+}
@@ -0,0 +1,207 @@
+package edf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DIGITAL_MIN = -32768
+	DIGITAL_MAX = 32767
+)
+
+// EDF+ 어노테이션 신호의 레이블
+const ANNOTATIONS_LABEL = "EDF Annotations"
+
+var ErrNoRecord = errors.New("No Data Record")
+
+// 하나의 신호, Samples는 Data Record 하나에 들어가는 샘플 수입니다.
+// PhysicalMin, PhysicalMax는 헤더에 8글자로 들어가므로 정수 정도로 맞추는 것이 좋습니다.
+type Signal struct {
+	Label       string
+	Transducer  string
+	Unit        string
+	PhysicalMin float64
+	PhysicalMax float64
+	Samples     int
+}
+
+// 시작 시각부터 Onset이 지난 Data Record, Data는 신호 순서대로 Samples개씩의 물리값입니다.
+type Record struct {
+	Onset time.Duration
+	Data  [][]float64
+}
+
+type Annotation struct {
+	Onset    time.Duration
+	Duration time.Duration
+	Text     string
+}
+
+// EDF+D(불연속) 파일, Data Record마다 시간 기록 TAL이 들어가므로 Record 사이가 비어도 됩니다.
+type File struct {
+	Patient        string // EDF+ 환자 항목 (코드 성별 생년월일 이름), 모르면 X
+	Equipment      string
+	Start          time.Time
+	RecordDuration time.Duration
+	Signals        []Signal
+	Records        []Record
+	Annotations    []Annotation
+}
+
+// ASCII 헤더 항목을 width 글자로 맞춥니다.
+func field(text string, width int) string {
+	text = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '_'
+		}
+
+		return r
+	}, text)
+
+	if len(text) > width {
+		return text[:width]
+	}
+
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+func number(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// 초 단위 소수 (TAL의 Onset, Duration)
+func seconds(duration time.Duration) string {
+	var text = strconv.FormatFloat(duration.Seconds(), 'f', -1, 64)
+	if duration >= 0 {
+		text = "+" + text
+	}
+
+	return text
+}
+
+func (file File) tals() [][]byte {
+	var sorted = append([]Annotation{}, file.Annotations...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Onset < sorted[j].Onset })
+
+	// 어노테이션은 Onset이 들어가는 Record에, 없으면 바로 앞의 Record에 넣습니다.
+	var tals = make([][]byte, len(file.Records))
+	for i, record := range file.Records {
+		tals[i] = []byte(seconds(record.Onset) + "\x14\x14\x00")
+	}
+
+	var index = 0
+	for _, annotation := range sorted {
+		for index+1 < len(file.Records) && file.Records[index+1].Onset <= annotation.Onset {
+			index++
+		}
+
+		var tal = seconds(annotation.Onset)
+		if annotation.Duration > 0 {
+			tal += "\x15" + strings.TrimPrefix(seconds(annotation.Duration), "+")
+		}
+
+		tals[index] = append(tals[index], []byte(tal+"\x14"+strings.ReplaceAll(annotation.Text, "\x14", " ")+"\x14\x00")...)
+	}
+
+	return tals
+}
+
+func (file File) WriteTo(w io.Writer) (n int64, err error) {
+	if len(file.Records) == 0 {
+		return 0, ErrNoRecord
+	}
+
+	var tals = file.tals()
+	var annotationSamples = 0
+	for _, tal := range tals {
+		if samples := (len(tal) + 1) / 2; samples > annotationSamples {
+			annotationSamples = samples
+		}
+	}
+
+	var signals = append(append([]Signal{}, file.Signals...), Signal{Label: ANNOTATIONS_LABEL, PhysicalMin: -1, PhysicalMax: 1, Samples: annotationSamples})
+	var start = file.Start.UTC()
+	var months = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	var patient = file.Patient
+	if patient == "" {
+		patient = "X X X X"
+	}
+
+	var buffer = bytes.Buffer{}
+	buffer.WriteString(field("0", 8))
+	buffer.WriteString(field(patient, 80))
+	buffer.WriteString(field(fmt.Sprintf("Startdate %02d-%s-%d X X %s", start.Day(), months[start.Month()-1], start.Year(), strings.ReplaceAll(nonEmpty(file.Equipment), " ", "_")), 80))
+	buffer.WriteString(start.Format("02.01.06"))
+	buffer.WriteString(start.Format("15.04.05"))
+	buffer.WriteString(field(strconv.Itoa(256*(len(signals)+1)), 8))
+	buffer.WriteString(field("EDF+D", 44))
+	buffer.WriteString(field(strconv.Itoa(len(file.Records)), 8))
+	buffer.WriteString(field(number(file.RecordDuration.Seconds()), 8))
+	buffer.WriteString(field(strconv.Itoa(len(signals)), 4))
+
+	var columns = []func(Signal) string{
+		func(signal Signal) string { return field(signal.Label, 16) },
+		func(signal Signal) string { return field(signal.Transducer, 80) },
+		func(signal Signal) string { return field(signal.Unit, 8) },
+		func(signal Signal) string { return field(number(signal.PhysicalMin), 8) },
+		func(signal Signal) string { return field(number(signal.PhysicalMax), 8) },
+		func(signal Signal) string { return field(strconv.Itoa(DIGITAL_MIN), 8) },
+		func(signal Signal) string { return field(strconv.Itoa(DIGITAL_MAX), 8) },
+		func(signal Signal) string { return field("", 80) },
+		func(signal Signal) string { return field(strconv.Itoa(signal.Samples), 8) },
+		func(signal Signal) string { return field("", 32) },
+	}
+
+	for _, column := range columns {
+		for _, signal := range signals {
+			buffer.WriteString(column(signal))
+		}
+	}
+
+	for i, record := range file.Records {
+		if len(record.Data) != len(file.Signals) {
+			return 0, fmt.Errorf("Record %d has %d Signals, Expected %d", i, len(record.Data), len(file.Signals))
+		}
+
+		for j, signal := range file.Signals {
+			if len(record.Data[j]) != signal.Samples {
+				return 0, fmt.Errorf("Record %d of %s has %d Samples, Expected %d", i, signal.Label, len(record.Data[j]), signal.Samples)
+			}
+
+			for _, value := range record.Data[j] {
+				binary.Write(&buffer, binary.LittleEndian, digital(signal, value))
+			}
+		}
+
+		buffer.Write(tals[i])
+		buffer.Write(make([]byte, 2*annotationSamples-len(tals[i])))
+	}
+
+	return buffer.WriteTo(w)
+}
+
+func nonEmpty(text string) string {
+	if text == "" {
+		return "X"
+	}
+
+	return text
+}
+
+func digital(signal Signal, value float64) int16 {
+	if math.IsNaN(value) || signal.PhysicalMax <= signal.PhysicalMin {
+		return 0
+	}
+
+	var scaled = (value-signal.PhysicalMin)/(signal.PhysicalMax-signal.PhysicalMin)*(DIGITAL_MAX-DIGITAL_MIN) + DIGITAL_MIN
+	return int16(math.Max(DIGITAL_MIN, math.Min(DIGITAL_MAX, math.Round(scaled))))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/storage"
+
+	"github.com/jessevdk/go-flags"
+)
+
+var ExportOptions struct {
+	Directory      string        `short:"D" long:"directory" description:"Directory of local storage"`
+	Inputs         []string      `short:"i" long:"input" description:"JSON Lines file of version 2 messages or storage segments (repeatable)"`
+	UDID           string        `short:"u" long:"udid" description:"Device to export, all devices when not given"`
+	From           string        `short:"f" long:"from" description:"Start of the range in RFC3339"`
+	To             string        `short:"t" long:"to" description:"End of the range in RFC3339"`
+	Format         string        `long:"format" description:"Output format" default:"edf" choice:"edf" choice:"csv"`
+	Output         string        `short:"o" long:"output" description:"Output directory" default:"."`
+	Rate           float64       `long:"rate" description:"Samples per second of EDF signals, estimated from data when 0"`
+	RecordDuration time.Duration `long:"record-duration" description:"Duration of one EDF data record" default:"1s"`
+}
+
+// 파일 이름으로 쓸 수 없는 문자
+var unsafeName = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// export 서브커맨드: 기록된 Waveform을 기기마다 EDF+ 파일이나 채널별 CSV 파일로 내보냅니다.
+// ex) signalize export -D /var/lib/signalize -f 2026-10-19T09:00:00+09:00 -t 2026-10-19T10:00:00+09:00 -o ./edf
+func RunExport(args []string) int {
+	if _, err := flags.ParseArgs(&ExportOptions, args); err != nil {
+		return 1
+	}
+
+	if ExportOptions.Directory == "" && len(ExportOptions.Inputs) == 0 {
+		log.Errorln("로컬 저장소(-D) 또는 입력 파일(-i)이 필요합니다.")
+		return 1
+	}
+
+	var query = storage.Query{UDID: ExportOptions.UDID}
+	for _, bound := range []struct {
+		text string
+		time *time.Time
+	}{{ExportOptions.From, &query.From}, {ExportOptions.To, &query.To}} {
+		if bound.text == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, bound.text)
+		if err != nil {
+			log.Errorln("시간은 RFC3339 형식이어야 합니다. (ex. 2026-10-19T09:00:00+09:00)")
+			log.Errorln(err)
+			return 1
+		}
+
+		*bound.time = parsed
+	}
+
+	var recordings = map[string]*storage.Recording{}
+	var order = []string{}
+	var add = func(entry storage.Entry) error {
+		// 입력 파일에는 여러 기기가 섞여 있을 수 있습니다.
+		if query.UDID != "" && entry.UDID != query.UDID {
+			return nil
+		}
+
+		recording, ok := recordings[entry.UDID]
+		if !ok {
+			recording = storage.NewRecording(entry.UDID)
+			recordings[entry.UDID] = recording
+			order = append(order, entry.UDID)
+		}
+
+		recording.Add(entry)
+		return nil
+	}
+
+	if ExportOptions.Directory != "" {
+		store, err := storage.OpenReadOnly(ExportOptions.Directory)
+		if err == nil {
+			err = store.Scan(query, add)
+		}
+
+		if err != nil {
+			log.Errorln("로컬 저장소를 읽지 못했습니다.")
+			log.Errorln(err)
+			return 1
+		}
+	}
+
+	for _, input := range ExportOptions.Inputs {
+		if err := storage.ScanFile(input, query, add); err != nil {
+			log.Errorln("입력 파일을 읽지 못했습니다.")
+			log.Errorln(err)
+			return 1
+		}
+	}
+
+	if err := os.MkdirAll(ExportOptions.Output, 0755); err != nil {
+		log.Errorln(err)
+		return 1
+	}
+
+	var status = 0
+	for _, udid := range order {
+		if err := exportRecording(recordings[udid]); err != nil {
+			log.Errorf("%s 기기를 내보내지 못했습니다.", udid)
+			log.Errorln(err)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// 파일 이름은 <UDID>-<시작 시각>.edf 또는 <UDID>-<시작 시각>-<채널>.csv입니다.
+func exportRecording(recording *storage.Recording) error {
+	if len(recording.Channels) == 0 {
+		return storage.ErrNoWaveform
+	}
+
+	var start = recording.Channels[0].Times[0]
+	for _, channel := range recording.Channels {
+		for _, timestamp := range channel.Times {
+			if timestamp.Before(start) {
+				start = timestamp
+			}
+		}
+	}
+
+	var prefix = filepath.Join(ExportOptions.Output, unsafeName.ReplaceAllString(recording.UDID, "_")+"-"+start.UTC().Format("20060102T150405Z"))
+	if ExportOptions.Format == "csv" {
+		for _, channel := range recording.Channels {
+			if err := writeFile(prefix+"-"+unsafeName.ReplaceAllString(channel.Key, "_")+".csv", channel.WriteCSV); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	file, err := recording.EDF(ExportOptions.Rate, ExportOptions.RecordDuration)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(prefix+".edf", func(output io.Writer) error {
+		_, err := file.WriteTo(output)
+		return err
+	})
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(output); err != nil {
+		output.Close()
+		return err
+	}
+
+	log.Infof("%s 파일을 썼습니다.", path)
+	return output.Close()
+}
@@ -0,0 +1,224 @@
+package fhir
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+)
+
+const (
+	LOINCSystem = "http://loinc.org"
+	UCUMSystem  = "http://unitsofmeasure.org"
+	// LOINC 코드가 없는 값은 Hamilton Identifier(Numeric)나 채널 이름(Waveform)을 로컬 코드로 사용합니다.
+	LocalSystem  = "urn:biosignal-hamilton-interface:parameter"
+	DeviceSystem = "urn:biosignal-hamilton-interface:udid"
+	MDCSystem    = "urn:iso:std:iso:11073:10101"
+)
+
+// Hamilton 단위와 UCUM 코드
+var UCUM = map[string]string{
+	"ml":        "mL",
+	"mL":        "mL",
+	"l/min":     "L/min",
+	"L/min":     "L/min",
+	"b/min":     "/min",
+	"1/min":     "/min",
+	"cmH2O":     "cm[H2O]",
+	"mmHg":      "mm[Hg]",
+	"%":         "%",
+	"s":         "s",
+	"ms":        "ms",
+	"kg":        "kg",
+	"cmH2O/l/s": "cm[H2O]/L/s",
+	"ml/cmH2O":  "mL/cm[H2O]",
+	"J/l":       "J/L",
+	"cmH2O*s":   "cm[H2O].s",
+}
+
+// LOINC 코드가 있는 Numeric 값
+var LOINC = map[int]Coding{
+	35:  {System: LOINCSystem, Code: "19889-5", Display: "Carbon dioxide [Partial pressure] in Exhaled gas --at end expiration"},
+	36:  {System: LOINCSystem, Code: "59408-5", Display: "Oxygen saturation in Arterial blood by Pulse oximetry"},
+	37:  {System: LOINCSystem, Code: "8867-4", Display: "Heart rate"},
+	43:  {System: LOINCSystem, Code: "20112-9", Display: "Tidal volume setting Ventilator"},
+	48:  {System: LOINCSystem, Code: "20077-4", Display: "Positive end expiratory pressure setting Ventilator"},
+	50:  {System: LOINCSystem, Code: "19994-3", Display: "Oxygen/Inspired gas setting [Volume Fraction] Ventilator"},
+	63:  {System: LOINCSystem, Code: "9279-1", Display: "Respiratory rate"},
+	71:  {System: LOINCSystem, Code: "3150-0", Display: "Inhaled oxygen concentration"},
+	110: {System: LOINCSystem, Code: "29463-7", Display: "Body weight"},
+}
+
+func Timestamp(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func quantity(value float64, unit string) *Quantity {
+	var q = &Quantity{Value: value, Unit: unit}
+	if code, ok := UCUM[unit]; ok {
+		q.System = UCUMSystem
+		q.Code = code
+	}
+
+	return q
+}
+
+// 값의 코드, LOINC와 MDC 코드가 있으면 로컬 코드와 같이 넣습니다.
+func NumericCode(model mq.QueueModel) CodeableConcept {
+	var identifier, key = model.IDENTIFIER, model.KEY
+	var concept = CodeableConcept{Text: key}
+	if coding, ok := LOINC[identifier]; ok {
+		concept.Coding = append(concept.Coding, coding)
+	}
+
+	if model.MDC_CODE != 0 {
+		concept.Coding = append(concept.Coding, Coding{System: MDCSystem, Code: strconv.Itoa(model.MDC_CODE), Display: model.MDC_REFID})
+	}
+
+	if identifier != 0 {
+		concept.Coding = append(concept.Coding, Coding{System: LocalSystem, Code: strconv.Itoa(identifier), Display: key})
+	} else {
+		concept.Coding = append(concept.Coding, Coding{System: LocalSystem, Code: key, Display: key})
+	}
+
+	return concept
+}
+
+func subject(model mq.QueueModel) *Reference {
+	if model.PATIENT_ID == "" {
+		return nil
+	}
+
+	return &Reference{Identifier: &Identifier{Value: model.PATIENT_ID}}
+}
+
+// Numeric 값 하나를 Observation으로 바꿉니다. device는 Bundle 안의 Device fullUrl입니다.
+func NumericObservation(model mq.QueueModel, device string) Observation {
+	return Observation{
+		ResourceType:      "Observation",
+		Status:            "final",
+		Code:              NumericCode(model),
+		Subject:           subject(model),
+		EffectiveDateTime: Timestamp(model.TIMESTAMP),
+		Device:            &Reference{Reference: device},
+		ValueQuantity:     quantity(model.NUMERIC_VALUE, model.VALUE_UNIT),
+	}
+}
+
+// 한 채널의 Waveform 샘플들을 SampledData Observation으로 바꿉니다.
+// 샘플 간격(Period)은 첫 샘플과 마지막 샘플 사이의 평균 간격입니다.
+func WaveformObservation(block []mq.QueueModel, device string) Observation {
+	var first, last = block[0], block[len(block)-1]
+
+	var values = []string{}
+	for _, model := range block {
+		for _, value := range model.WAVEFORM_VALUE {
+			values = append(values, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+	}
+
+	var period = float64(0)
+	if len(values) > 1 {
+		period = float64(last.TIMESTAMP.Sub(first.TIMESTAMP)) / float64(time.Millisecond) / float64(len(values)-1)
+	}
+
+	var origin = quantity(0, first.VALUE_UNIT)
+	return Observation{
+		ResourceType: "Observation",
+		Status:       "final",
+		Code:         NumericCode(first),
+		Subject:      subject(first),
+		EffectivePeriod: &Period{
+			Start: Timestamp(first.TIMESTAMP),
+			End:   Timestamp(last.TIMESTAMP),
+		},
+		Device: &Reference{Reference: device},
+		ValueSampledData: &SampledData{
+			Origin:     *origin,
+			Period:     period,
+			Factor:     1,
+			Dimensions: 1,
+			Data:       strings.Join(values, " "),
+		},
+	}
+}
+
+// 벤틸레이터를 나타내는 Device, number는 Identifier 86으로 받은 벤틸레이터 번호입니다.
+func NewDevice(udid string, number string) Device {
+	return Device{
+		ResourceType: "Device",
+		Identifier:   []Identifier{{System: DeviceSystem, Value: udid}},
+		Status:       "active",
+		Manufacturer: "Hamilton Medical",
+		DeviceName:   []DeviceName{{Name: "Hamilton Ventilator", Type: "manufacturer-name"}},
+		SerialNumber: number,
+		Type:         &CodeableConcept{Text: "Ventilator"},
+	}
+}
+
+// version은 UUID 버전을 상위 4bit에 둔 값입니다. (ex. 0x40, 0x50)
+func formatUUID(b []byte, version byte) string {
+	b[6] = b[6]&0x0F | version
+	b[8] = b[8]&0x3F | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// RFC 4122 Appendix C의 이름 공간
+const (
+	NamespaceDNS = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	NamespaceURL = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+)
+
+// 같은 이름 공간과 이름이면 항상 같은 버전 5(SHA-1 이름 기반, RFC 4122 4.3) urn:uuid (Device처럼 Bundle마다 같은 fullUrl을 써야 하는 경우)
+func NameUUID(namespace string, name string) string {
+	space, err := hex.DecodeString(strings.Replace(namespace, "-", "", -1))
+	if err != nil || len(space) != 16 {
+		panic("Invalid Namespace UUID: " + namespace)
+	}
+
+	var hash = sha1.New()
+	hash.Write(space)
+	hash.Write([]byte(name))
+	return formatUUID(hash.Sum(nil)[:16], 0x50)
+}
+
+// Device의 fullUrl. 다른 도구에서도 NamespaceURL과 "DeviceSystem:udid"로 같은 값을 만들 수 있습니다.
+func DeviceUUID(udid string) string {
+	return NameUUID(NamespaceURL, DeviceSystem+":"+udid)
+}
+
+func RandomUUID() string {
+	var b = make([]byte, 16)
+	rand.Read(b)
+	return formatUUID(b, 0x40)
+}
+
+// Device는 식별자로 조건부 업데이트(PUT)하고, Observation은 새로 만드는(POST) transaction Bundle
+func NewBundle(device Device, observations []Observation) Bundle {
+	var deviceURL = DeviceUUID(device.Identifier[0].Value)
+	var bundle = Bundle{
+		ResourceType: "Bundle",
+		Type:         "transaction",
+		Timestamp:    Timestamp(time.Now()),
+		Entry: []BundleEntry{{
+			FullURL:  deviceURL,
+			Resource: device,
+			Request:  &BundleRequest{Method: "PUT", URL: "Device?identifier=" + DeviceSystem + "|" + device.Identifier[0].Value},
+		}},
+	}
+
+	for _, observation := range observations {
+		bundle.Entry = append(bundle.Entry, BundleEntry{
+			FullURL:  RandomUUID(),
+			Resource: observation,
+			Request:  &BundleRequest{Method: "POST", URL: "Observation"},
+		})
+	}
+
+	return bundle
+}
@@ -0,0 +1,93 @@
+package fhir
+
+// FHIR R4 리소스 중 이 프로젝트에서 사용하는 필드만 정의합니다.
+// https://hl7.org/fhir/R4/
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value"`
+}
+
+type Reference struct {
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+	Display    string      `json:"display,omitempty"`
+}
+
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// 일정한 간격(Period, ms)으로 측정한 값들, Data는 공백으로 구분한 (값 - Origin) / Factor
+type SampledData struct {
+	Origin     Quantity `json:"origin"`
+	Period     float64  `json:"period"`
+	Factor     float64  `json:"factor,omitempty"`
+	Dimensions int      `json:"dimensions"`
+	Data       string   `json:"data"`
+}
+
+type Observation struct {
+	ResourceType      string            `json:"resourceType"`
+	Status            string            `json:"status"`
+	Category          []CodeableConcept `json:"category,omitempty"`
+	Code              CodeableConcept   `json:"code"`
+	Subject           *Reference        `json:"subject,omitempty"`
+	EffectiveDateTime string            `json:"effectiveDateTime,omitempty"`
+	EffectivePeriod   *Period           `json:"effectivePeriod,omitempty"`
+	Device            *Reference        `json:"device,omitempty"`
+	ValueQuantity     *Quantity         `json:"valueQuantity,omitempty"`
+	ValueSampledData  *SampledData      `json:"valueSampledData,omitempty"`
+}
+
+type DeviceName struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type Device struct {
+	ResourceType string           `json:"resourceType"`
+	Identifier   []Identifier     `json:"identifier,omitempty"`
+	Status       string           `json:"status,omitempty"`
+	Manufacturer string           `json:"manufacturer,omitempty"`
+	DeviceName   []DeviceName     `json:"deviceName,omitempty"`
+	SerialNumber string           `json:"serialNumber,omitempty"`
+	Type         *CodeableConcept `json:"type,omitempty"`
+}
+
+type BundleRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type BundleEntry struct {
+	FullURL  string         `json:"fullUrl,omitempty"`
+	Resource interface{}    `json:"resource"`
+	Request  *BundleRequest `json:"request,omitempty"`
+}
+
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp,omitempty"`
+	Entry        []BundleEntry `json:"entry"`
+}
@@ -0,0 +1,187 @@
+package fhir
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+)
+
+// Numeric 값과 Waveform을 Observation으로 모아 Interval마다 transaction Bundle로 내보내는 출력
+// Endpoint가 있으면 FHIR 서버에 POST하고, 없으면 Directory에 JSON 파일로 씁니다.
+// Waveform은 채널마다 BlockSize개의 샘플을 모아 하나의 SampledData Observation으로 만듭니다.
+type BundleSink struct {
+	Endpoint   string
+	Directory  string
+	Device     Device
+	BlockSize  int
+	Interval   time.Duration
+	MaxBacklog int // 보내지 못하고 쌓아둘 Observation 수, 넘치면 오래된 것부터 버립니다. 0이면 제한 없음
+	Client     *http.Client
+	OnError    func(error)
+
+	lock         sync.Mutex
+	observations []Observation
+	blocks       map[string][]mq.QueueModel
+	done         chan struct{}
+	start        sync.Once
+	wait         sync.WaitGroup
+}
+
+// Endpoint가 없으면 Directory를 만듭니다. 만들지 못하면 Flush에서 에러가 납니다.
+func NewBundleSink(endpoint string, directory string, device Device) *BundleSink {
+	if endpoint == "" && directory != "" {
+		os.MkdirAll(directory, 0755)
+	}
+
+	return &BundleSink{
+		Endpoint:   endpoint,
+		Directory:  directory,
+		Device:     device,
+		BlockSize:  50,
+		Interval:   10 * time.Second,
+		MaxBacklog: 10000,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		blocks:     map[string][]mq.QueueModel{},
+		done:       make(chan struct{}),
+	}
+}
+
+// 처음 Send를 호출할 때 Interval마다 Flush하는 고루틴을 시작합니다.
+func (sink *BundleSink) Send(d mq.QueueModel) error {
+	sink.start.Do(func() {
+		sink.wait.Add(1)
+		go sink.run()
+	})
+
+	var device = DeviceUUID(sink.Device.Identifier[0].Value)
+
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+
+	switch d.TYPE {
+	case "Numeric":
+		sink.observations = append(sink.observations, NumericObservation(d, device))
+	case "Waveform":
+		var block = append(sink.blocks[d.KEY], d)
+		if len(block) >= sink.BlockSize {
+			sink.observations = append(sink.observations, WaveformObservation(block, device))
+			block = nil
+		}
+
+		sink.blocks[d.KEY] = block
+	}
+
+	return nil
+}
+
+func (sink *BundleSink) run() {
+	defer sink.wait.Done()
+
+	var ticker = time.NewTicker(sink.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-sink.done:
+			return
+		}
+
+		if err := sink.Flush(); err != nil && sink.OnError != nil {
+			sink.OnError(err)
+		}
+	}
+}
+
+// 모인 Observation을 Bundle로 내보냅니다. 실패한 Observation은 다음 Flush에서 다시 보냅니다.
+// 다시 보낼 Observation이 MaxBacklog를 넘으면 오래된 것부터 버리고 OnError로 알립니다.
+func (sink *BundleSink) Flush() error {
+	sink.lock.Lock()
+	var observations = sink.observations
+	sink.observations = nil
+	sink.lock.Unlock()
+
+	if len(observations) == 0 {
+		return nil
+	}
+
+	var bundle = NewBundle(sink.Device, observations)
+	var err = sink.Write(bundle)
+	if err != nil {
+		var dropped = 0
+		sink.lock.Lock()
+		sink.observations = append(observations, sink.observations...)
+		if sink.MaxBacklog > 0 && len(sink.observations) > sink.MaxBacklog {
+			dropped = len(sink.observations) - sink.MaxBacklog
+			sink.observations = append([]Observation{}, sink.observations[dropped:]...)
+		}
+		sink.lock.Unlock()
+
+		if dropped > 0 && sink.OnError != nil {
+			sink.OnError(fmt.Errorf("Dropped %d Oldest Observations over Backlog Limit %d", dropped, sink.MaxBacklog))
+		}
+	}
+
+	return err
+}
+
+func (sink *BundleSink) Write(bundle Bundle) error {
+	body, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+
+	if sink.Endpoint == "" {
+		var name = fmt.Sprintf("bundle-%s.json", time.Now().UTC().Format("20060102T150405.000000000"))
+		return ioutil.WriteFile(filepath.Join(sink.Directory, name), body, 0644)
+	}
+
+	request, err := http.NewRequest("POST", sink.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/fhir+json")
+	request.Header.Set("Accept", "application/fhir+json")
+
+	response, err := sink.Client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("FHIR server responded %s: %s", response.Status, message)
+	}
+
+	return nil
+}
+
+// 남은 Observation을 보내고 종료합니다. 채우지 못한 Waveform 블록도 보냅니다.
+func (sink *BundleSink) Close() error {
+	sink.start.Do(func() {})
+	close(sink.done)
+	sink.wait.Wait()
+
+	var device = DeviceUUID(sink.Device.Identifier[0].Value)
+	sink.lock.Lock()
+	for key, block := range sink.blocks {
+		if len(block) > 0 {
+			sink.observations = append(sink.observations, WaveformObservation(block, device))
+		}
+
+		delete(sink.blocks, key)
+	}
+	sink.lock.Unlock()
+
+	return sink.Flush()
+}
@@ -0,0 +1,123 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+)
+
+// MSH에 들어가는 송수신 애플리케이션 정보
+type Header struct {
+	SendingApplication   string
+	SendingFacility      string
+	ReceivingApplication string
+	ReceivingFacility    string
+}
+
+// PID, PV1에 들어가는 환자 정보
+// Name은 "FAMILY^GIVEN"처럼 HL7 컴포넌트 형태로 넣습니다.
+type Patient struct {
+	ID                 string
+	AssigningAuthority string
+	Name               string
+	Class              string // PV1-2, 기본값 I(입원)
+	Location           string // PV1-3, ex. "ICU^01^A"
+}
+
+const (
+	segmentSeparator = "\r"
+	timestampFormat  = "20060102150405.000-0700"
+)
+
+var escaper = strings.NewReplacer(
+	`\`, `\E\`,
+	`|`, `\F\`,
+	`^`, `\S\`,
+	`&`, `\T\`,
+	`~`, `\R\`,
+)
+
+// 구분자로 쓰이는 문자를 이스케이프합니다. (HL7 v2.5 2.7)
+func Escape(value string) string {
+	return escaper.Replace(value)
+}
+
+func segment(fields ...string) string {
+	return strings.Join(fields, "|")
+}
+
+// 하나의 Numeric 값을 ORU^R01 메시지로 만듭니다.
+// OBX-3은 Hamilton Identifier가 있으면 "Identifier^이름^HAMILTON", 없으면 "KEY^KEY^HAMILTON" 형태입니다.
+// MDC 코드가 있으면 "^CODE^REFID^MDC"를 뒤에 붙입니다.
+func EncodeORU(model mq.QueueModel, header Header, patient Patient, controlID string) []byte {
+	var patientID = patient.ID
+	if patientID == "" {
+		patientID = model.PATIENT_ID
+	}
+
+	var class = patient.Class
+	if class == "" {
+		class = "I"
+	}
+
+	var code = Escape(model.KEY) + "^" + Escape(model.KEY) + "^HAMILTON"
+	if model.IDENTIFIER != 0 {
+		code = strconv.Itoa(model.IDENTIFIER) + "^" + Escape(model.KEY) + "^HAMILTON"
+	}
+
+	// IEEE 11073 숫자 코드가 있으면 대체 코드(OBX-3.4 ~ 3.6)로 넣습니다.
+	if model.MDC_CODE != 0 {
+		code += "^" + strconv.Itoa(model.MDC_CODE) + "^" + model.MDC_REFID + "^MDC"
+	}
+
+	var timestamp = model.TIMESTAMP.Format(timestampFormat)
+	var segments = []string{
+		segment("MSH", `^~\&`, Escape(header.SendingApplication), Escape(header.SendingFacility),
+			Escape(header.ReceivingApplication), Escape(header.ReceivingFacility),
+			time.Now().Format(timestampFormat), "", "ORU^R01^ORU_R01", Escape(controlID), "P", "2.5"),
+		segment("PID", "1", "", Escape(patientID)+"^^^"+Escape(patient.AssigningAuthority), "", patient.Name),
+		segment("PV1", "1", class, patient.Location),
+		segment("OBR", "1", "", Escape(controlID), "HAMILTON^Hamilton Ventilator^L", "", "", timestamp),
+		segment("OBX", "1", "NM", code, "", strconv.FormatFloat(model.NUMERIC_VALUE, 'f', -1, 64),
+			Escape(model.VALUE_UNIT), "", "", "", "", "F", "", "", timestamp, "", "", "", Escape(model.UDID)),
+	}
+
+	return []byte(strings.Join(segments, segmentSeparator) + segmentSeparator)
+}
+
+// ACK 메시지의 MSA 세그먼트
+type Acknowledgment struct {
+	Code      string // AA, AE, AR (또는 CA, CE, CR)
+	ControlID string
+	Text      string
+}
+
+func (ack Acknowledgment) Accepted() bool {
+	return ack.Code == "AA" || ack.Code == "CA"
+}
+
+func ParseACK(raw []byte) (Acknowledgment, error) {
+	for _, line := range strings.FieldsFunc(string(raw), func(r rune) bool { return r == '\r' || r == '\n' }) {
+		var fields = strings.Split(line, "|")
+		if fields[0] != "MSA" {
+			continue
+		}
+
+		if len(fields) < 3 {
+			return Acknowledgment{}, fmt.Errorf("Invalid MSA Segment %q", line)
+		}
+
+		var ack = Acknowledgment{Code: fields[1], ControlID: fields[2]}
+		if len(fields) > 3 {
+			ack.Text = fields[3]
+		}
+
+		return ack, nil
+	}
+
+	return Acknowledgment{}, errors.New("Missing MSA Segment")
+}
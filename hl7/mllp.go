@@ -0,0 +1,210 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+)
+
+// MLLP 프레임 (HL7 v2.5 Appendix C)
+const (
+	startBlock = 0x0B
+	endBlock   = 0x1C
+	carriage   = 0x0D
+)
+
+var ErrQueueFull = errors.New("HL7 Queue is Full")
+
+// 수신측이 AR(CR)로 거부한 메시지, 다시 보내도 같으므로 재시도하지 않습니다.
+var ErrRejected = errors.New("HL7 Message Rejected")
+
+func Frame(message []byte) []byte {
+	var frame = append([]byte{startBlock}, message...)
+	return append(frame, endBlock, carriage)
+}
+
+// MLLP 프레임 하나를 읽어 메시지를 반환합니다.
+func ReadFrame(reader *bufio.Reader) ([]byte, error) {
+	if _, err := reader.ReadBytes(startBlock); err != nil {
+		return nil, err
+	}
+
+	message, err := reader.ReadBytes(endBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	if next, err := reader.ReadByte(); err != nil || next != carriage {
+		return nil, errors.New("Missing MLLP Trailer")
+	}
+
+	return bytes.TrimSuffix(message, []byte{endBlock}), nil
+}
+
+// Numeric 값을 ORU^R01로 바꿔 MLLP로 보내는 출력
+// Send는 큐에 넣기만 하고, 별도의 고루틴에서 ACK를 받을 때까지 Retries번 다시 보냅니다.
+type MLLPSink struct {
+	Address    string
+	Header     Header
+	Patient    Patient
+	Types      map[string]bool // 보낼 TYPE, 기본값 Numeric
+	Timeout    time.Duration
+	Retries    int
+	RetryDelay time.Duration
+	Session    string // Control ID(MSH-10)에 붙여 재시작해도 겹치지 않게 합니다. 기본값은 시작 시각
+	OnError    func(error)
+
+	queue    chan mq.QueueModel
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence int
+	wait     sync.WaitGroup
+}
+
+func NewMLLPSink(address string, header Header, patient Patient) *MLLPSink {
+	var sink = &MLLPSink{
+		Address:    address,
+		Header:     header,
+		Patient:    patient,
+		Types:      map[string]bool{"Numeric": true},
+		Timeout:    5 * time.Second,
+		Retries:    3,
+		RetryDelay: time.Second,
+		Session:    strconv.FormatInt(time.Now().Unix(), 36),
+		queue:      make(chan mq.QueueModel, 1024),
+	}
+
+	sink.wait.Add(1)
+	go sink.run()
+
+	return sink
+}
+
+func (sink *MLLPSink) Send(d mq.QueueModel) error {
+	if !sink.Types[d.TYPE] {
+		return nil
+	}
+
+	select {
+	case sink.queue <- d:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// 큐에 남은 메시지를 모두 보낸 뒤 연결을 닫습니다.
+func (sink *MLLPSink) Close() error {
+	close(sink.queue)
+	sink.wait.Wait()
+
+	if sink.conn != nil {
+		return sink.conn.Close()
+	}
+
+	return nil
+}
+
+// 순번은 36진수 6자리로 쓰고, 36^6개마다 처음으로 돌아갑니다.
+const (
+	sequenceDigits = 6
+	sequenceLimit  = 36 * 36 * 36 * 36 * 36 * 36
+)
+
+// MSH-10 Control ID. HL7 v2의 20자 제한을 넘지 않도록 UDID 앞 6자, session 뒤 8자, 6자리 36진수 순번을 이어 붙입니다.
+func ControlID(udid string, session string, sequence int) string {
+	if len(udid) > 6 {
+		udid = udid[:6]
+	}
+
+	if len(session) > 8 {
+		session = session[len(session)-8:]
+	}
+
+	var number = strconv.FormatUint(uint64(sequence)%sequenceLimit, 36)
+	return udid + session + strings.Repeat("0", sequenceDigits-len(number)) + number
+}
+
+func (sink *MLLPSink) run() {
+	defer sink.wait.Done()
+
+	for model := range sink.queue {
+		sink.sequence++
+		var controlID = ControlID(model.UDID, sink.Session, sink.sequence)
+		if err := sink.Deliver(EncodeORU(model, sink.Header, sink.Patient, controlID), controlID); err != nil && sink.OnError != nil {
+			sink.OnError(err)
+		}
+	}
+}
+
+// 메시지를 보내고 AA ACK를 받을 때까지 다시 시도합니다. AR로 거부되면 바로 포기합니다.
+func (sink *MLLPSink) Deliver(message []byte, controlID string) error {
+	var err error
+	for attempt := 0; attempt <= sink.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(sink.RetryDelay)
+		}
+
+		if err = sink.exchange(message, controlID); err == nil {
+			return nil
+		} else if errors.Is(err, ErrRejected) {
+			break
+		}
+	}
+
+	return fmt.Errorf("HL7 message %s was not delivered to %s: %v", controlID, sink.Address, err)
+}
+
+func (sink *MLLPSink) exchange(message []byte, controlID string) error {
+	if sink.conn == nil {
+		conn, err := net.DialTimeout("tcp", sink.Address, sink.Timeout)
+		if err != nil {
+			return err
+		}
+
+		sink.conn = conn
+		sink.reader = bufio.NewReader(conn)
+	}
+
+	sink.conn.SetDeadline(time.Now().Add(sink.Timeout))
+
+	var response []byte
+	_, err := sink.conn.Write(Frame(message))
+	if err == nil {
+		response, err = ReadFrame(sink.reader)
+	}
+
+	// 연결에 문제가 있으면 다음 시도에서 다시 연결합니다.
+	if err != nil {
+		sink.conn.Close()
+		sink.conn = nil
+		return err
+	}
+
+	ack, err := ParseACK(response)
+	if err != nil {
+		return err
+	}
+
+	if ack.ControlID != controlID {
+		return fmt.Errorf("ACK for %s, expected %s", ack.ControlID, controlID)
+	}
+
+	if ack.Code == "AR" || ack.Code == "CR" {
+		return fmt.Errorf("%w with %s: %s", ErrRejected, ack.Code, ack.Text)
+	}
+
+	if !ack.Accepted() {
+		return fmt.Errorf("HL7 message rejected with %s: %s", ack.Code, ack.Text)
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package live
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+	"github.com/Hazealign/biosignal-hamilton-interface/storage"
+
+	"github.com/gorilla/websocket"
+)
+
+// 받을 데이터, UDID가 비어있으면 모든 기기, Types나 Keys가 비어있으면 모든 타입, 키입니다.
+// Waveform은 채널마다 Block개의 샘플을 모아서 보냅니다.
+type Filter struct {
+	UDID  string   `json:"udid"`
+	Types []string `json:"types"`
+	Keys  []string `json:"keys"`
+	Block int      `json:"block"`
+}
+
+func ParseFilter(values url.Values) Filter {
+	var filter = Filter{UDID: values.Get("udid"), Block: 1}
+	if text := values.Get("type"); text != "" {
+		filter.Types = strings.Split(text, ",")
+	}
+
+	if text := values.Get("key"); text != "" {
+		filter.Keys = strings.Split(text, ",")
+	}
+
+	if block, err := strconv.Atoi(values.Get("block")); err == nil && block > 0 {
+		filter.Block = block
+	}
+
+	return filter
+}
+
+func (filter Filter) Match(d mq.QueueModel) bool {
+	return (filter.UDID == "" || filter.UDID == d.UDID) && contains(filter.Types, d.TYPE) && contains(filter.Keys, d.KEY)
+}
+
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 느린 클라이언트 때문에 버린 메시지 수를 알리는 메시지
+type Dropped struct {
+	Type  string `json:"type"` // 항상 "Dropped"
+	Count uint64 `json:"count"`
+}
+
+type client struct {
+	conn    *websocket.Conn
+	filter  Filter
+	send    chan []byte
+	blocks  map[string]*storage.Entry
+	dropped uint64
+}
+
+// 연결된 WebSocket 클라이언트마다 Filter에 맞는 데이터를 JSON(storage.Entry의 형태)으로 보내는 출력
+// 클라이언트마다 Buffer개까지 쌓아두고, 가득 차면 새 메시지를 버린 뒤 다음에 보낼 수 있을 때 Dropped 메시지로 알립니다.
+// WriteTimeout 안에 보내지 못하는 클라이언트는 연결을 끊습니다.
+// 클라이언트는 연결한 뒤에 Filter를 JSON으로 보내 받을 데이터를 바꿀 수 있습니다.
+type Hub struct {
+	Buffer       int
+	WriteTimeout time.Duration
+	PingInterval time.Duration
+	Upgrader     websocket.Upgrader
+
+	lock    sync.Mutex
+	clients map[*client]bool
+	dropped uint64
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		Buffer:       256,
+		WriteTimeout: 10 * time.Second,
+		PingInterval: 30 * time.Second,
+		clients:      map[*client]bool{},
+	}
+}
+
+// 주어진 Origin에서 온 연결도 받습니다. (없으면 같은 Origin만)
+func (hub *Hub) AllowOrigins(origins []string) {
+	if len(origins) == 0 {
+		return
+	}
+
+	hub.Upgrader.CheckOrigin = func(r *http.Request) bool {
+		var origin = r.Header.Get("Origin")
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+
+		return origin == "" || strings.EqualFold(origin, "http://"+r.Host) || strings.EqualFold(origin, "https://"+r.Host)
+	}
+}
+
+func (hub *Hub) Send(d mq.QueueModel) error {
+	var entry = storage.Entry{Record: d.Record()}
+	var message []byte
+
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	for client := range hub.clients {
+		if !client.filter.Match(d) {
+			continue
+		}
+
+		if d.TYPE == "Waveform" && client.filter.Block > 1 {
+			block, ok := client.blocks[d.UDID+"\x00"+d.KEY]
+			if !ok {
+				var first = entry
+				block = &first
+				client.blocks[d.UDID+"\x00"+d.KEY] = block
+			} else {
+				block.WaveformValue = append(append([]float64{}, block.WaveformValue...), entry.WaveformValue...)
+				block.WaveformRaw = append(append([]int{}, block.WaveformRaw...), entry.WaveformRaw...)
+				block.Quality = entry.Quality
+			}
+
+			var end = entry.Timestamp
+			block.End = &end
+			if len(block.WaveformValue) < client.filter.Block {
+				continue
+			}
+
+			delete(client.blocks, d.UDID+"\x00"+d.KEY)
+			encoded, err := json.Marshal(block)
+			if err != nil {
+				return err
+			}
+
+			hub.enqueue(client, encoded)
+			continue
+		}
+
+		if message == nil {
+			var err error
+			if message, err = json.Marshal(entry); err != nil {
+				return err
+			}
+		}
+
+		hub.enqueue(client, message)
+	}
+
+	return nil
+}
+
+// 잠금을 잡은 상태에서 호출해야 합니다.
+func (hub *Hub) enqueue(client *client, message []byte) {
+	if client.dropped > 0 {
+		notice, _ := json.Marshal(Dropped{Type: "Dropped", Count: client.dropped})
+		select {
+		case client.send <- notice:
+			client.dropped = 0
+		default:
+		}
+	}
+
+	select {
+	case client.send <- message:
+	default:
+		client.dropped++
+		atomic.AddUint64(&hub.dropped, 1)
+	}
+}
+
+// GET ?udid=&type=&key=&block= 을 WebSocket으로 바꿉니다.
+func (hub *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := hub.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	var client = &client{
+		conn:   conn,
+		filter: ParseFilter(r.URL.Query()),
+		send:   make(chan []byte, hub.Buffer),
+		blocks: map[string]*storage.Entry{},
+	}
+
+	hub.lock.Lock()
+	hub.clients[client] = true
+	hub.lock.Unlock()
+
+	go hub.write(client)
+	hub.read(client)
+}
+
+func (hub *Hub) remove(client *client) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	if hub.clients[client] {
+		delete(hub.clients, client)
+		close(client.send)
+	}
+}
+
+// 클라이언트가 보내는 Filter를 읽습니다. 연결이 끊기면 클라이언트를 지웁니다.
+func (hub *Hub) read(client *client) {
+	defer client.conn.Close()
+	defer hub.remove(client)
+
+	client.conn.SetReadLimit(4096)
+	client.conn.SetReadDeadline(time.Now().Add(2 * hub.PingInterval))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(2 * hub.PingInterval))
+	})
+
+	for {
+		_, message, err := client.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var filter Filter
+		if err := json.Unmarshal(message, &filter); err != nil {
+			continue
+		}
+
+		if filter.Block < 1 {
+			filter.Block = 1
+		}
+
+		hub.lock.Lock()
+		client.filter = filter
+		client.blocks = map[string]*storage.Entry{}
+		hub.lock.Unlock()
+	}
+}
+
+func (hub *Hub) write(client *client) {
+	var ticker = time.NewTicker(hub.PingInterval)
+	defer ticker.Stop()
+	defer client.conn.Close()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(hub.WriteTimeout))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(hub.WriteTimeout))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// 연결된 클라이언트 수
+func (hub *Hub) Clients() int {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	return len(hub.clients)
+}
+
+// 느린 클라이언트 때문에 버린 메시지 수
+func (hub *Hub) Dropped() uint64 {
+	return atomic.LoadUint64(&hub.dropped)
+}
+
+// 모든 클라이언트의 연결을 닫습니다.
+func (hub *Hub) Close() error {
+	hub.lock.Lock()
+	var clients = []*client{}
+	for client := range hub.clients {
+		clients = append(clients, client)
+	}
+	hub.lock.Unlock()
+
+	for _, client := range clients {
+		hub.remove(client)
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	COUNTER   = "counter"
+	GAUGE     = "gauge"
+	HISTOGRAM = "histogram"
+)
+
+// 하나의 시계열 값, Histogram은 Suffix(_bucket, _sum, _count)가 붙습니다.
+type Sample struct {
+	Suffix string
+	Labels map[string]string
+	Value  float64
+}
+
+// 이름이 같은 시계열들, Collect는 /metrics를 요청할 때마다 호출됩니다.
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Collect func() []Sample
+}
+
+// Prometheus 텍스트 형식(0.0.4)으로 내보내는 Metric 모음
+type Registry struct {
+	lock    sync.Mutex
+	metrics []Metric
+}
+
+var Default = &Registry{}
+
+func (registry *Registry) Register(metric Metric) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.metrics = append(registry.metrics, metric)
+}
+
+func (registry *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	registry.lock.Lock()
+	var metrics = append([]Metric{}, registry.metrics...)
+	registry.lock.Unlock()
+
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+
+	var buffer = bytes.Buffer{}
+	for _, metric := range metrics {
+		fmt.Fprintf(&buffer, "# HELP %s %s\n", metric.Name, escape(metric.Help, false))
+		fmt.Fprintf(&buffer, "# TYPE %s %s\n", metric.Name, metric.Type)
+
+		for _, sample := range metric.Collect() {
+			buffer.WriteString(metric.Name)
+			buffer.WriteString(sample.Suffix)
+			buffer.WriteString(labels(sample.Labels))
+			buffer.WriteString(" ")
+			buffer.WriteString(value(sample.Value))
+			buffer.WriteString("\n")
+		}
+	}
+
+	return buffer.WriteTo(w)
+}
+
+func (registry *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	registry.WriteTo(w)
+}
+
+func labels(values map[string]string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	var names = []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var pairs = []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escape(values[name], true)))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func value(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escape(text string, quote bool) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	text = strings.ReplaceAll(text, "\n", `\n`)
+	if quote {
+		text = strings.ReplaceAll(text, `"`, `\"`)
+	}
+
+	return text
+}
+
+// 값의 분포, Buckets는 오름차순인 상한들입니다.
+type Histogram struct {
+	Buckets []float64
+
+	lock   sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func NewHistogram(buckets ...float64) *Histogram {
+	sort.Float64s(buckets)
+	return &Histogram{Buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (histogram *Histogram) Observe(v float64) {
+	histogram.lock.Lock()
+	defer histogram.lock.Unlock()
+
+	for i, bound := range histogram.Buckets {
+		if v <= bound {
+			histogram.counts[i]++
+		}
+	}
+
+	histogram.sum += v
+	histogram.count++
+}
+
+// labels를 붙인 _bucket(누적), _sum, _count
+func (histogram *Histogram) Samples(labels map[string]string) []Sample {
+	histogram.lock.Lock()
+	defer histogram.lock.Unlock()
+
+	var with = func(le string) map[string]string {
+		var values = map[string]string{"le": le}
+		for name, value := range labels {
+			values[name] = value
+		}
+
+		return values
+	}
+
+	var samples = []Sample{}
+	for i, bound := range histogram.Buckets {
+		samples = append(samples, Sample{Suffix: "_bucket", Labels: with(value(bound)), Value: float64(histogram.counts[i])})
+	}
+
+	return append(samples,
+		Sample{Suffix: "_bucket", Labels: with("+Inf"), Value: float64(histogram.count)},
+		Sample{Suffix: "_sum", Labels: labels, Value: histogram.sum},
+		Sample{Suffix: "_count", Labels: labels, Value: float64(histogram.count)},
+	)
+}
@@ -0,0 +1,173 @@
+package mq
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrSinkClosed = errors.New("Sink Closed")
+
+// MPUB 한 번의 결과
+type BatchReport struct {
+	Topic   string
+	Count   int
+	Bytes   int
+	Waited  time.Duration // 가장 먼저 들어온 메시지가 기다린 시간
+	Latency time.Duration // MPUB에 걸린 시간
+	Err     error
+}
+
+type batch struct {
+	bodies [][]byte
+	bytes  int
+	first  time.Time
+}
+
+// NSQSink의 메시지를 토픽별로 모아서 MultiPublish로 보내는 출력
+// MaxCount개나 MaxBytes가 차면 Send를 호출한 쪽에서 바로 보내고, 그 전에는 Window마다 모인 것을 보냅니다.
+// Window마다 보내는 중의 오류는 OnError로 넘기며, 결과는 모두 OnBatch로 넘깁니다.
+type BatchSink struct {
+	NSQSink  NSQSink // Pool이 있어야 합니다.
+	MaxCount int
+	MaxBytes int
+	Window   time.Duration
+	OnBatch  func(BatchReport)
+	OnError  func(error)
+
+	lock    sync.Mutex
+	pending map[string]*batch
+	closed  bool
+	start   sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func NewBatchSink(sink NSQSink, maxCount int, maxBytes int, window time.Duration) *BatchSink {
+	return &BatchSink{
+		NSQSink:  sink,
+		MaxCount: maxCount,
+		MaxBytes: maxBytes,
+		Window:   window,
+		pending:  map[string]*batch{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (sink *BatchSink) Send(d QueueModel) error {
+	topics, message, err := sink.NSQSink.Message(d)
+	if err != nil {
+		return err
+	}
+
+	sink.start.Do(func() { go sink.run() })
+
+	var full = map[string]*batch{}
+	sink.lock.Lock()
+	if sink.closed {
+		sink.lock.Unlock()
+		return ErrSinkClosed
+	}
+
+	for _, topic := range topics {
+		var pending, ok = sink.pending[topic]
+		if !ok {
+			pending = &batch{first: time.Now()}
+			sink.pending[topic] = pending
+		}
+
+		pending.bodies = append(pending.bodies, message)
+		pending.bytes += len(message)
+		if (sink.MaxCount > 0 && len(pending.bodies) >= sink.MaxCount) || (sink.MaxBytes > 0 && pending.bytes >= sink.MaxBytes) {
+			full[topic] = pending
+			delete(sink.pending, topic)
+		}
+	}
+	sink.lock.Unlock()
+
+	for topic, pending := range full {
+		if report := sink.publish(topic, pending); report.Err != nil {
+			return report.Err
+		}
+	}
+
+	return nil
+}
+
+func (sink *BatchSink) publish(topic string, pending *batch) BatchReport {
+	var started = time.Now()
+	var err = sink.NSQSink.Pool.MultiPublish(topic, pending.bodies)
+	var report = BatchReport{
+		Topic:   topic,
+		Count:   len(pending.bodies),
+		Bytes:   pending.bytes,
+		Waited:  started.Sub(pending.first),
+		Latency: time.Since(started),
+		Err:     err,
+	}
+
+	if sink.OnBatch != nil {
+		sink.OnBatch(report)
+	}
+
+	return report
+}
+
+// 모인 메시지를 모두 보냅니다.
+func (sink *BatchSink) Flush() error {
+	sink.lock.Lock()
+	var pending = sink.pending
+	sink.pending = map[string]*batch{}
+	sink.lock.Unlock()
+
+	var err error
+	for topic, batch := range pending {
+		if report := sink.publish(topic, batch); report.Err != nil {
+			err = report.Err
+		}
+	}
+
+	return err
+}
+
+func (sink *BatchSink) run() {
+	defer close(sink.stopped)
+
+	var window = sink.Window
+	if window <= 0 {
+		window = 50 * time.Millisecond
+	}
+
+	var ticker = time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := sink.Flush(); err != nil && sink.OnError != nil {
+				sink.OnError(err)
+			}
+		case <-sink.done:
+			return
+		}
+	}
+}
+
+// 남은 메시지를 보내고 닫습니다. 이후의 Send는 ErrSinkClosed를 반환합니다.
+func (sink *BatchSink) Close() error {
+	sink.lock.Lock()
+	if sink.closed {
+		sink.lock.Unlock()
+		return nil
+	}
+
+	sink.closed = true
+	sink.lock.Unlock()
+
+	sink.start.Do(func() { close(sink.stopped) })
+	close(sink.done)
+	<-sink.stopped
+
+	return sink.Flush()
+}
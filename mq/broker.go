@@ -0,0 +1,357 @@
+package mq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitly/go-nsq"
+)
+
+var ErrNoBroker = errors.New("No NSQ Broker Available")
+
+// nsqd 하나에 보내는 연결 (테스트에서는 가짜로 바꿀 수 있습니다)
+type Publisher interface {
+	Publish(topic string, body []byte) error
+	MultiPublish(topic string, body [][]byte) error
+	DeferredPublish(topic string, delay time.Duration, body []byte) error
+	Ping() error
+	Stop()
+}
+
+func DialNSQ(address string, config *nsq.Config) (Publisher, error) {
+	return nsq.NewProducer(address, config)
+}
+
+// nsqd의 상태
+type BrokerHealth struct {
+	Address    string
+	Healthy    bool
+	Discovered bool // nsqlookupd에서 찾은 nsqd
+	Published  uint64
+	Failures   uint64
+	LastError  string
+	DownSince  time.Time
+}
+
+type broker struct {
+	BrokerHealth
+	publisher Publisher
+}
+
+// 여러 nsqd 중 정상인 곳으로 보내고, 실패하면 다음 nsqd로 넘어갑니다.
+// FanOut이면 정상인 모든 nsqd로 보냅니다. 실패한 nsqd는 RetryAfter가 지나거나 Check에서 Ping이 성공하면 다시 사용합니다.
+type BrokerPool struct {
+	Config     *nsq.Config
+	Lookupd    []string // nsqlookupd HTTP 주소 (ex. http://lookupd:4161)
+	FanOut     bool
+	RetryAfter time.Duration
+	Dial       func(address string, config *nsq.Config) (Publisher, error)
+	Client     *http.Client
+
+	lock    sync.Mutex
+	brokers []*broker
+	done    chan struct{}
+}
+
+func NewBrokerPool(addresses []string, config *nsq.Config) *BrokerPool {
+	var pool = &BrokerPool{
+		Config:     config,
+		RetryAfter: 30 * time.Second,
+		Dial:       DialNSQ,
+		Client:     &http.Client{Timeout: 5 * time.Second},
+		done:       make(chan struct{}),
+	}
+
+	for _, address := range addresses {
+		pool.add(address, false)
+	}
+
+	return pool
+}
+
+func (pool *BrokerPool) add(address string, discovered bool) {
+	for _, broker := range pool.brokers {
+		if broker.Address == address {
+			return
+		}
+	}
+
+	pool.brokers = append(pool.brokers, &broker{
+		BrokerHealth: BrokerHealth{Address: address, Healthy: true, Discovered: discovered},
+	})
+}
+
+// 보낼 차례인 nsqd들 (정상인 것 먼저, 순서는 추가된 순서)
+func (pool *BrokerPool) candidates(now time.Time) []*broker {
+	var healthy, retry []*broker
+	for _, broker := range pool.brokers {
+		if broker.Healthy {
+			healthy = append(healthy, broker)
+		} else if now.Sub(broker.DownSince) >= pool.RetryAfter {
+			retry = append(retry, broker)
+		}
+	}
+
+	return append(healthy, retry...)
+}
+
+// broker의 연결을 가져오거나 새로 만듭니다. 느린 nsqd가 다른 nsqd를 막지 않도록 잠금 없이 연결합니다.
+func (pool *BrokerPool) connect(broker *broker) (Publisher, error) {
+	pool.lock.Lock()
+	var publisher = broker.publisher
+	pool.lock.Unlock()
+
+	if publisher != nil {
+		return publisher, nil
+	}
+
+	publisher, err := pool.Dial(broker.Address, pool.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	// 그 사이에 다른 곳에서 먼저 연결했으면 그 연결을 씁니다.
+	if broker.publisher != nil {
+		publisher.Stop()
+		return broker.publisher, nil
+	}
+
+	broker.publisher = publisher
+	return publisher, nil
+}
+
+// 잠금 없이 send를 실행하고, 결과를 broker의 상태에 기록합니다.
+func (pool *BrokerPool) try(broker *broker, send func(Publisher) error) error {
+	publisher, err := pool.connect(broker)
+	if err == nil {
+		err = send(publisher)
+	}
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if err != nil {
+		broker.Failures++
+		broker.LastError = err.Error()
+		broker.Healthy = false
+		// 다시 시도해서 실패해도 RetryAfter만큼 기다린 뒤에 다시 시도합니다.
+		broker.DownSince = time.Now()
+
+		return err
+	}
+
+	broker.Published++
+	broker.Healthy = true
+	return nil
+}
+
+// send를 정상인 nsqd 하나(FanOut이면 모두)에 실행합니다. 하나도 성공하지 못하면 error를 반환합니다.
+func (pool *BrokerPool) Do(send func(Publisher) error) error {
+	pool.lock.Lock()
+	var candidates = pool.candidates(time.Now())
+	pool.lock.Unlock()
+
+	var errs = []string{}
+	var succeeded = 0
+	for _, broker := range candidates {
+		if err := pool.try(broker, send); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", broker.Address, err))
+			continue
+		}
+
+		succeeded++
+		if !pool.FanOut {
+			break
+		}
+	}
+
+	if succeeded == 0 {
+		if len(errs) == 0 {
+			return ErrNoBroker
+		}
+
+		return fmt.Errorf("%w (%s)", ErrNoBroker, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (pool *BrokerPool) Publish(topics []string, body []byte) error {
+	return pool.Do(func(publisher Publisher) error {
+		for _, topic := range topics {
+			if err := publisher.Publish(topic, body); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// bodies를 topic에 MPUB 한 번으로 보냅니다.
+func (pool *BrokerPool) MultiPublish(topic string, bodies [][]byte) error {
+	return pool.Do(func(publisher Publisher) error {
+		return publisher.MultiPublish(topic, bodies)
+	})
+}
+
+// delay가 지난 뒤에 소비자에게 전달되도록 DPUB로 보냅니다.
+func (pool *BrokerPool) DeferredPublish(topics []string, delay time.Duration, body []byte) error {
+	return pool.Do(func(publisher Publisher) error {
+		for _, topic := range topics {
+			if err := publisher.DeferredPublish(topic, delay, body); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// 실패한 nsqd에 Ping을 보내 다시 사용할 수 있는지 확인합니다.
+func (pool *BrokerPool) Check() {
+	var failed = []*broker{}
+	pool.lock.Lock()
+	for _, broker := range pool.brokers {
+		if !broker.Healthy {
+			failed = append(failed, broker)
+		}
+	}
+	pool.lock.Unlock()
+
+	for _, broker := range failed {
+		pool.try(broker, func(publisher Publisher) error { return publisher.Ping() })
+	}
+}
+
+type lookupNodes struct {
+	Producers []struct {
+		BroadcastAddress string `json:"broadcast_address"`
+		TCPPort          int    `json:"tcp_port"`
+	} `json:"producers"`
+}
+
+// nsqlookupd의 /nodes에서 nsqd를 찾아 추가합니다.
+func (pool *BrokerPool) Discover() error {
+	var found = []string{}
+	var errs = []string{}
+	for _, lookupd := range pool.Lookupd {
+		if !strings.HasPrefix(lookupd, "http") {
+			lookupd = "http://" + lookupd
+		}
+
+		response, err := pool.Client.Get(strings.TrimSuffix(lookupd, "/") + "/nodes")
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			response.Body.Close()
+			errs = append(errs, fmt.Sprintf("%s: %s", lookupd, response.Status))
+			continue
+		}
+
+		var body struct {
+			lookupNodes
+			Data *lookupNodes `json:"data"` // 이전 버전의 nsqlookupd
+		}
+
+		err = json.NewDecoder(response.Body).Decode(&body)
+		response.Body.Close()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", lookupd, err))
+			continue
+		}
+
+		var nodes = body.lookupNodes
+		if body.Data != nil {
+			nodes = *body.Data
+		}
+
+		for _, producer := range nodes.Producers {
+			found = append(found, net.JoinHostPort(producer.BroadcastAddress, strconv.Itoa(producer.TCPPort)))
+		}
+	}
+
+	pool.lock.Lock()
+	for _, address := range found {
+		pool.add(address, true)
+	}
+	pool.lock.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("nsqlookupd: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Watch의 interval이 0 이하일 때 쓰는 간격
+const DefaultWatchInterval = 30 * time.Second
+
+// interval마다 Discover와 Check를 실행합니다. 오류는 onError로 넘깁니다.
+func (pool *BrokerPool) Watch(interval time.Duration, onError func(error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	go func() {
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if len(pool.Lookupd) > 0 {
+				if err := pool.Discover(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+
+			pool.Check()
+
+			select {
+			case <-ticker.C:
+			case <-pool.done:
+				return
+			}
+		}
+	}()
+}
+
+func (pool *BrokerPool) Health() []BrokerHealth {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	var health = []BrokerHealth{}
+	for _, broker := range pool.brokers {
+		health = append(health, broker.BrokerHealth)
+	}
+
+	sort.Slice(health, func(i, j int) bool { return health[i].Address < health[j].Address })
+	return health
+}
+
+func (pool *BrokerPool) Stop() {
+	close(pool.done)
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	for _, broker := range pool.brokers {
+		if broker.publisher != nil {
+			broker.publisher.Stop()
+			broker.publisher = nil
+		}
+	}
+}
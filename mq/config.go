@@ -0,0 +1,112 @@
+package mq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/bitly/go-nsq"
+)
+
+// NSQ Producer의 연결 설정 (TLS, 인증, 압축)
+// 값이 비어 있으면 기존처럼 평문 TCP로 연결합니다.
+type ProducerConfig struct {
+	TLS                bool
+	CAFile             string // 서버 인증서를 검증할 CA 번들 (PEM), 비어 있으면 시스템 CA
+	CertFile           string // 클라이언트 인증서 (PEM)
+	KeyFile            string
+	ServerName         string // 인증서의 이름과 비교할 서버 이름, 비어 있으면 주소의 호스트
+	InsecureSkipVerify bool
+
+	AuthSecret string
+
+	Deflate      bool
+	DeflateLevel int // 1 ~ 9, 0이면 6
+	Snappy       bool
+}
+
+func (config ProducerConfig) NSQConfig() (*nsq.Config, error) {
+	var result = nsq.NewConfig()
+
+	if config.Deflate && config.Snappy {
+		return nil, errors.New("Deflate and Snappy Cannot be Used Together")
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, errors.New("Client Certificate Needs Both Cert and Key File")
+	}
+
+	if !config.TLS && (config.CAFile != "" || config.CertFile != "" || config.ServerName != "") {
+		return nil, errors.New("TLS Options are Given without TLS")
+	}
+
+	if config.TLS {
+		var tlsConfig = &tls.Config{
+			InsecureSkipVerify: config.InsecureSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		}
+
+		if config.CAFile != "" {
+			pem, err := ioutil.ReadFile(config.CAFile)
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("No Certificate in CA File %q", config.CAFile)
+			}
+		}
+
+		if config.CertFile != "" {
+			certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+
+		// go-nsq는 연결할 때 ServerName을 주소의 호스트로 덮어쓰므로, 다른 이름은 직접 검증합니다.
+		if config.ServerName != "" && !config.InsecureSkipVerify {
+			tlsConfig.InsecureSkipVerify = true
+			tlsConfig.VerifyConnection = verifyServerName(config.ServerName, tlsConfig.RootCAs)
+		}
+
+		result.TlsV1 = true
+		result.TlsConfig = tlsConfig
+	}
+
+	result.AuthSecret = config.AuthSecret
+	result.Deflate = config.Deflate
+	if config.DeflateLevel != 0 {
+		result.DeflateLevel = config.DeflateLevel
+	}
+	result.Snappy = config.Snappy
+
+	return result, result.Validate()
+}
+
+// 서버 인증서가 roots(nil이면 시스템 CA)로 검증되고 name에 대한 것인지 확인합니다.
+func verifyServerName(name string, roots *x509.CertPool) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("No Server Certificate")
+		}
+
+		var intermediates = x509.NewCertPool()
+		for _, certificate := range state.PeerCertificates[1:] {
+			intermediates.AddCert(certificate)
+		}
+
+		_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+			DNSName:       name,
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+
+		return err
+	}
+}
@@ -0,0 +1,408 @@
+package mq
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	CONTENT_TYPE_JSON_V1     = "application/json; version=1"
+	CONTENT_TYPE_JSON_V2     = "application/json; version=2"
+	CONTENT_TYPE_PROTOBUF    = "application/x-protobuf; version=2"
+	CONTENT_TYPE_MESSAGEPACK = "application/msgpack; version=2"
+)
+
+// QueueModel을 보낼 형태로 바꾸는 인코더, 출력마다 다르게 지정할 수 있습니다.
+type Encoder interface {
+	ContentType() string
+	Encode(d QueueModel) ([]byte, error)
+}
+
+// "json", "protobuf", "msgpack" 중 하나의 인코더를 반환합니다. version은 JSON에만 쓰입니다.
+func NewEncoder(name string, version int) (Encoder, error) {
+	switch strings.ToLower(name) {
+	case "", "json":
+		return JSONEncoder{Version: version}, nil
+	case "protobuf", "proto":
+		return ProtobufEncoder{}, nil
+	case "msgpack", "messagepack":
+		return MessagePackEncoder{}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown Encoding %q", name)
+}
+
+type JSONEncoder struct {
+	Version int
+}
+
+func (encoder JSONEncoder) ContentType() string {
+	if encoder.Version == SCHEMA_VERSION_2 {
+		return CONTENT_TYPE_JSON_V2
+	}
+
+	return CONTENT_TYPE_JSON_V1
+}
+
+func (encoder JSONEncoder) Encode(d QueueModel) ([]byte, error) {
+	return Encode(d, encoder.Version)
+}
+
+// Record를 schema/record.proto의 형태로 인코딩합니다.
+type ProtobufEncoder struct{}
+
+func (ProtobufEncoder) ContentType() string {
+	return CONTENT_TYPE_PROTOBUF
+}
+
+func (ProtobufEncoder) Encode(d QueueModel) ([]byte, error) {
+	return MarshalProtobuf(d.Record()), nil
+}
+
+// Record를 JSON과 같은 필드 이름의 MessagePack으로 인코딩합니다.
+type MessagePackEncoder struct{}
+
+func (MessagePackEncoder) ContentType() string {
+	return CONTENT_TYPE_MESSAGEPACK
+}
+
+func (MessagePackEncoder) Encode(d QueueModel) ([]byte, error) {
+	var buffer bytes.Buffer
+	var encoder = msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+
+	err := encoder.Encode(d.Record())
+	return buffer.Bytes(), err
+}
+
+func UnmarshalMessagePack(raw []byte) (record Record, err error) {
+	var decoder = msgpack.NewDecoder(bytes.NewReader(raw))
+	decoder.SetCustomStructTag("json")
+
+	err = decoder.Decode(&record)
+	return
+}
+
+// NSQ 메시지에는 헤더가 없으므로, JSON이 아닌 형태는 앞에 Content-Type과 줄바꿈을 붙입니다.
+// JSON은 기존 소비자를 위해 그대로 보냅니다.
+func Wrap(contentType string, body []byte) []byte {
+	if strings.HasPrefix(contentType, "application/json") {
+		return body
+	}
+
+	var message = make([]byte, 0, len(contentType)+1+len(body))
+	message = append(message, contentType...)
+	message = append(message, '\n')
+	return append(message, body...)
+}
+
+// Wrap한 메시지에서 Content-Type과 내용을 꺼냅니다. '{'로 시작하면 JSON입니다.
+// 서명된 메시지는 KeyRing.Verify로 먼저 검증해야 합니다.
+func Unwrap(message []byte) (contentType string, body []byte, err error) {
+	if bytes.HasPrefix(message, []byte(signaturePrefix)) {
+		return "", nil, errors.New("Signed Message, Verify First")
+	}
+
+	if len(message) > 0 && message[0] == '{' {
+		if bytes.Contains(message, []byte(`"schema_version":2`)) {
+			return CONTENT_TYPE_JSON_V2, message, nil
+		}
+
+		return CONTENT_TYPE_JSON_V1, message, nil
+	}
+
+	var index = bytes.IndexByte(message, '\n')
+	if index < 0 {
+		return "", nil, errors.New("Missing Content-Type")
+	}
+
+	return string(message[:index]), message[index+1:], nil
+}
+
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendVarint(b []byte, number protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+func appendDouble(b []byte, number protowire.Number, value float64) []byte {
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+func appendTimestamp(b []byte, number protowire.Number, t time.Time) []byte {
+	var message []byte
+	message = appendVarint(message, 1, uint64(t.Unix()))
+	message = appendVarint(message, 2, uint64(t.Nanosecond()))
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+func MarshalProtobuf(record Record) []byte {
+	var b = make([]byte, 0, 128+len(record.WaveformValue)*8)
+	b = appendVarint(b, 1, uint64(record.SchemaVersion))
+	b = appendString(b, 2, record.Session)
+	b = appendVarint(b, 3, record.Sequence)
+	b = appendTimestamp(b, 4, record.Timestamp)
+	b = appendString(b, 5, record.Type)
+	b = appendString(b, 6, record.Key)
+	b = appendVarint(b, 7, uint64(record.Identifier))
+	b = appendVarint(b, 8, uint64(record.MDCCode))
+	b = appendString(b, 9, record.MDCRefID)
+	b = appendString(b, 10, record.Device)
+	b = appendString(b, 11, record.UDID)
+	b = appendString(b, 12, record.Host)
+	b = appendString(b, 13, record.Port)
+	b = appendString(b, 14, record.PatientID)
+	b = appendString(b, 15, record.Unit)
+
+	if record.NumericValue != nil {
+		b = appendDouble(b, 16, *record.NumericValue)
+	}
+
+	if len(record.WaveformValue) > 0 {
+		var packed = make([]byte, 0, len(record.WaveformValue)*8)
+		for _, value := range record.WaveformValue {
+			packed = protowire.AppendFixed64(packed, math.Float64bits(value))
+		}
+
+		b = protowire.AppendTag(b, 17, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed)
+	}
+
+	if len(record.WaveformRaw) > 0 {
+		var packed []byte
+		for _, value := range record.WaveformRaw {
+			packed = protowire.AppendVarint(packed, protowire.EncodeZigZag(int64(value)))
+		}
+
+		b = protowire.AppendTag(b, 18, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed)
+	}
+
+	for _, source := range record.Sources {
+		b = protowire.AppendTag(b, 19, protowire.BytesType)
+		b = protowire.AppendString(b, source)
+	}
+
+	b = appendString(b, 20, record.Quality)
+
+	if trend := record.Trend; trend != nil {
+		var message []byte
+		message = appendString(message, 1, trend.Interval)
+		message = appendTimestamp(message, 2, trend.Start)
+		message = appendDouble(message, 3, trend.Min)
+		message = appendDouble(message, 4, trend.Max)
+		message = appendDouble(message, 5, trend.Mean)
+		message = appendDouble(message, 6, trend.Median)
+		message = appendDouble(message, 7, trend.Last)
+		message = appendVarint(message, 8, uint64(trend.Count))
+		message = appendVarint(message, 9, uint64(trend.Invalid))
+
+		b = protowire.AppendTag(b, 21, protowire.BytesType)
+		b = protowire.AppendBytes(b, message)
+	}
+
+	return b
+}
+
+// 메시지의 필드를 차례대로 읽어 field를 호출합니다.
+func consumeFields(raw []byte, field func(number protowire.Number, kind protowire.Type, value []byte) int) error {
+	for len(raw) > 0 {
+		number, kind, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		raw = raw[n:]
+		if n = field(number, kind, raw); n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		raw = raw[n:]
+	}
+
+	return nil
+}
+
+func consumeTimestamp(raw []byte) (t time.Time, err error) {
+	var seconds, nanos uint64
+	err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		switch {
+		case number == 1 && kind == protowire.VarintType:
+			var n int
+			seconds, n = protowire.ConsumeVarint(value)
+			return n
+		case number == 2 && kind == protowire.VarintType:
+			var n int
+			nanos, n = protowire.ConsumeVarint(value)
+			return n
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+
+	return time.Unix(int64(seconds), int64(nanos)).UTC(), err
+}
+
+func UnmarshalProtobuf(raw []byte) (record Record, err error) {
+	var nested error
+	err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		var n int
+		switch kind {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(value)
+			switch number {
+			case 1:
+				record.SchemaVersion = int(v)
+			case 3:
+				record.Sequence = v
+			case 7:
+				record.Identifier = int(int32(v))
+			case 8:
+				record.MDCCode = int(int32(v))
+			}
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(value)
+			if number == 16 {
+				var numeric = math.Float64frombits(v)
+				record.NumericValue = &numeric
+			}
+		case protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(value)
+			if n < 0 {
+				return n
+			}
+
+			nested = record.setBytes(number, v)
+		default:
+			n = protowire.ConsumeFieldValue(number, kind, value)
+		}
+
+		if nested != nil {
+			return -1
+		}
+
+		return n
+	})
+
+	if nested != nil {
+		err = nested
+	}
+
+	return
+}
+
+func (record *Record) setBytes(number protowire.Number, v []byte) (err error) {
+	switch number {
+	case 2:
+		record.Session = string(v)
+	case 4:
+		record.Timestamp, err = consumeTimestamp(v)
+	case 5:
+		record.Type = string(v)
+	case 6:
+		record.Key = string(v)
+	case 9:
+		record.MDCRefID = string(v)
+	case 10:
+		record.Device = string(v)
+	case 11:
+		record.UDID = string(v)
+	case 12:
+		record.Host = string(v)
+	case 13:
+		record.Port = string(v)
+	case 14:
+		record.PatientID = string(v)
+	case 15:
+		record.Unit = string(v)
+	case 17:
+		for len(v) >= 8 {
+			value, _ := protowire.ConsumeFixed64(v)
+			record.WaveformValue = append(record.WaveformValue, math.Float64frombits(value))
+			v = v[8:]
+		}
+	case 18:
+		for len(v) > 0 {
+			value, n := protowire.ConsumeVarint(v)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+
+			record.WaveformRaw = append(record.WaveformRaw, int(protowire.DecodeZigZag(value)))
+			v = v[n:]
+		}
+	case 19:
+		record.Sources = append(record.Sources, string(v))
+	case 20:
+		record.Quality = string(v)
+	case 21:
+		record.Trend, err = consumeTrend(v)
+	}
+
+	return
+}
+
+func consumeTrend(raw []byte) (*TrendRecord, error) {
+	var trend = &TrendRecord{}
+	var nested error
+	var err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		switch {
+		case number == 1 && kind == protowire.BytesType:
+			v, n := protowire.ConsumeString(value)
+			trend.Interval = v
+			return n
+		case number == 2 && kind == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(value)
+			if n >= 0 {
+				trend.Start, nested = consumeTimestamp(v)
+			}
+			return n
+		case number >= 3 && number <= 7 && kind == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(value)
+			var fields = []*float64{&trend.Min, &trend.Max, &trend.Mean, &trend.Median, &trend.Last}
+			*fields[number-3] = math.Float64frombits(v)
+			return n
+		case (number == 8 || number == 9) && kind == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(value)
+			if number == 8 {
+				trend.Count = int(v)
+			} else {
+				trend.Invalid = int(v)
+			}
+			return n
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+
+	if nested != nil {
+		return nil, nested
+	}
+
+	return trend, err
+}
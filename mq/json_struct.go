@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/bitly/go-nsq"
 )
@@ -12,14 +14,58 @@ type QueueModel struct {
 	TIMESTAMP      time.Time
 	TYPE           string
 	KEY            string
+	IDENTIFIER     int    `json:",omitempty"` // Numeric 값의 Hamilton Identifier
+	MDC_CODE       int    `json:",omitempty"` // IEEE 11073-10101 숫자 코드
+	MDC_REFID      string `json:",omitempty"` // IEEE 11073-10101 Reference ID
 	PORT           string
 	HOST           string
 	VALUE_UNIT     string
 	UDID           string
 	DEVICE         string
 	NUMERIC_VALUE  float64
-	WAVEFORM_VALUE []int
+	WAVEFORM_VALUE []float64
+	WAVEFORM_RAW   []int `json:",omitempty"` // 보정 전 12bit 값
 	PATIENT_ID     string
+	SOURCES        []string         `json:",omitempty"` // Derived 값을 계산하는데 사용한 값들
+	QUALITY        string           `json:",omitempty"` // Waveform의 신호 품질
+	TREND          *TrendStatistics `json:",omitempty"`
+	SESSION        string           `json:"-"` // 버전 2에만 들어갑니다.
+	SEQUENCE       uint64           `json:"-"`
+}
+
+// Trend 타입에 들어가는 요약값, COUNT가 0이면 유효한 값이 없었던 것입니다.
+type TrendStatistics struct {
+	INTERVAL string
+	START    time.Time
+	MIN      float64
+	MAX      float64
+	MEAN     float64
+	MEDIAN   float64
+	LAST     float64
+	COUNT    int
+	INVALID  int
+}
+
+// 읽은 Numeric 값의 모델, 알람 상태(packet.IsAlarm)는 측정값과 구분하도록 Alarm 타입으로 만듭니다.
+func NumericModel(identifier int, value float64, unit string, timestamp time.Time) QueueModel {
+	var model = QueueModel{
+		TIMESTAMP:     timestamp,
+		TYPE:          "Numeric",
+		KEY:           packet.TypeIntString[identifier],
+		IDENTIFIER:    identifier,
+		VALUE_UNIT:    unit,
+		NUMERIC_VALUE: value,
+	}
+
+	if packet.IsAlarm(identifier) {
+		model.TYPE = "Alarm"
+		return model
+	}
+
+	var nomenclature = packet.Nomenclatures[identifier]
+	model.MDC_CODE = nomenclature.Code
+	model.MDC_REFID = nomenclature.RefID
+	return model
 }
 
 func (d *QueueModel) MarshalJSON() ([]byte, error) {
@@ -37,14 +83,39 @@ func (d *QueueModel) MarshalJSON() ([]byte, error) {
 }
 
 func SendToNSQ(d QueueModel, str string) error {
+	return PublishToNSQ(d, str, "Biosignal")
+}
+
+// 내용을 str 주소의 NSQ에 topic으로 보냅니다.
+func PublishToNSQ(d QueueModel, str string, topic string) error {
 	d.DEVICE = "Hamilton"
 	d.PATIENT_ID = "TEST_ID"
 
-	config := nsq.NewConfig()
-	producer, _ := nsq.NewProducer(str, config)
-
 	jsonVal, _ := d.MarshalJSON()
-	logrus.Println(string(jsonVal))
-	err := producer.Publish("Biosignal", jsonVal)
-	return err
+	return publish(nsq.NewConfig(), str, []string{topic}, jsonVal, 0)
+}
+
+// 하나의 연결로 topics에 모두 보냅니다. delay가 있으면 DPUB로 보냅니다.
+func publish(config *nsq.Config, str string, topics []string, body []byte, delay time.Duration) error {
+	producer, err := nsq.NewProducer(str, config)
+	if err != nil {
+		return err
+	}
+
+	defer producer.Stop()
+
+	logrus.Println(string(body))
+	for _, topic := range topics {
+		if delay > 0 {
+			err = producer.DeferredPublish(topic, delay, body)
+		} else {
+			err = producer.Publish(topic, body)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,132 @@
+package mq
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nsqd의 토픽 이름 규칙
+var topicPattern = regexp.MustCompile(`^[.a-zA-Z0-9_-]+(#ephemeral)?$`)
+var topicInvalid = regexp.MustCompile(`[^.a-zA-Z0-9_-]+`)
+
+// 조건이 맞는 데이터를 Topics로 보내는 규칙
+// 비어 있는 조건은 모든 값과 맞습니다. Topics에는 {type}, {key}, {identifier}, {udid}, {session}을 쓸 수 있습니다.
+type Route struct {
+	Type   string
+	Key    string
+	UDID   string
+	Topics []string
+}
+
+func (route Route) Match(d QueueModel) bool {
+	return (route.Type == "" || strings.EqualFold(route.Type, d.TYPE)) &&
+		(route.Key == "" || route.Key == d.KEY) &&
+		(route.UDID == "" || route.UDID == d.UDID)
+}
+
+// 맞는 규칙들의 토픽으로 모두 보내고, 맞는 규칙이 없으면 Default로 보냅니다.
+type Router struct {
+	Routes  []Route
+	Default []string
+}
+
+// "type=Waveform,key=FLOW,udid=...=>topic1,topic2" 형태의 규칙을 읽습니다. 조건이 없으면 "*=>topic"
+func ParseRoute(rule string) (Route, error) {
+	var parts = strings.SplitN(rule, "=>", 2)
+	if len(parts) != 2 {
+		return Route{}, fmt.Errorf("Invalid Route %q, use MATCH=>TOPIC[,TOPIC]", rule)
+	}
+
+	var route = Route{}
+	if match := strings.TrimSpace(parts[0]); match != "*" && match != "" {
+		for _, condition := range strings.Split(match, ",") {
+			var pair = strings.SplitN(condition, "=", 2)
+			if len(pair) != 2 {
+				return Route{}, fmt.Errorf("Invalid Condition %q", condition)
+			}
+
+			switch strings.ToLower(strings.TrimSpace(pair[0])) {
+			case "type":
+				route.Type = pair[1]
+			case "key":
+				route.Key = pair[1]
+			case "udid", "device":
+				route.UDID = pair[1]
+			default:
+				return Route{}, fmt.Errorf("Unknown Condition %q, use type, key or udid", pair[0])
+			}
+		}
+	}
+
+	for _, topic := range strings.Split(parts[1], ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			route.Topics = append(route.Topics, topic)
+		}
+	}
+
+	if len(route.Topics) == 0 {
+		return Route{}, fmt.Errorf("No Topic in Route %q", rule)
+	}
+
+	// 예시 값으로 템플릿을 확인합니다.
+	for _, topic := range route.Topics {
+		if _, err := ExpandTopic(topic, QueueModel{TYPE: "Numeric", KEY: "f total", IDENTIFIER: 63, UDID: "0123456789abcdef0123456789abcdef01234567", SESSION: "0011223344556677"}); err != nil {
+			return Route{}, err
+		}
+	}
+
+	return route, nil
+}
+
+func topicValue(value string) string {
+	return strings.Trim(topicInvalid.ReplaceAllString(value, "_"), "_")
+}
+
+// 토픽 템플릿을 채웁니다. 값에 토픽 이름으로 쓸 수 없는 문자가 있으면 '_'로 바꿉니다.
+func ExpandTopic(template string, d QueueModel) (string, error) {
+	var topic = strings.NewReplacer(
+		"{type}", topicValue(strings.ToLower(d.TYPE)),
+		"{key}", topicValue(d.KEY),
+		"{identifier}", strconv.Itoa(d.IDENTIFIER),
+		"{udid}", topicValue(d.UDID),
+		"{session}", topicValue(d.SESSION),
+	).Replace(template)
+
+	if len(topic) == 0 || len(topic) > 64 || !topicPattern.MatchString(topic) {
+		return "", fmt.Errorf("Invalid Topic %q from Template %q", topic, template)
+	}
+
+	return topic, nil
+}
+
+// 데이터를 보낼 토픽들 (중복 제외)
+func (router Router) Topics(d QueueModel) ([]string, error) {
+	var templates = []string{}
+	for _, route := range router.Routes {
+		if route.Match(d) {
+			templates = append(templates, route.Topics...)
+		}
+	}
+
+	if len(templates) == 0 {
+		templates = router.Default
+	}
+
+	var topics = []string{}
+	var seen = map[string]bool{}
+	for _, template := range templates {
+		topic, err := ExpandTopic(template, d)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics, nil
+}
@@ -0,0 +1,189 @@
+package mq
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	SCHEMA_VERSION_1 = 1 // QueueModel.MarshalJSON의 형태 (Scheduler 호환)
+	SCHEMA_VERSION_2 = 2 // Record의 형태
+)
+
+const CurrentSchemaVersion = SCHEMA_VERSION_2
+
+// 버전 2의 메시지 형태
+// Timestamp는 RFC3339Nano이며, Sequence는 Session 안에서 기기(UDID)마다 1부터 증가합니다.
+type Record struct {
+	SchemaVersion int          `json:"schema_version" description:"Version of this schema, always 2"`
+	Session       string       `json:"session" description:"Identifier of the acquisition session, changes when the interface restarts"`
+	Sequence      uint64       `json:"sequence" description:"Sequence number per device in the session, starting from 1"`
+	Timestamp     time.Time    `json:"timestamp" description:"Time of measurement in RFC3339 with nanoseconds"`
+	Type          string       `json:"type" description:"Numeric, Alarm, Waveform, Breath, Derived, Event, Diagnostic, Status or Trend"`
+	Key           string       `json:"key" description:"Parameter name or waveform channel"`
+	Identifier    int          `json:"identifier,omitempty" description:"Hamilton parameter identifier of numerics"`
+	MDCCode       int          `json:"mdc_code,omitempty" description:"IEEE 11073-10101 numeric code"`
+	MDCRefID      string       `json:"mdc_ref_id,omitempty" description:"IEEE 11073-10101 reference ID"`
+	Device        string       `json:"device" description:"Manufacturer of the device"`
+	UDID          string       `json:"udid" description:"Device identifier derived from the ventilator number"`
+	Host          string       `json:"host" description:"Address of the interface and serial port"`
+	Port          string       `json:"port" description:"Serial port connected with the device"`
+	PatientID     string       `json:"patient_id,omitempty" description:"Patient identifier when known"`
+	Unit          string       `json:"unit,omitempty" description:"Unit of numeric_value or waveform_value"`
+	NumericValue  *float64     `json:"numeric_value,omitempty" description:"Value of every type except Waveform and Trend without valid values"`
+	WaveformValue []float64    `json:"waveform_value,omitempty" description:"Calibrated waveform samples"`
+	WaveformRaw   []int        `json:"waveform_raw,omitempty" description:"Raw 12bit waveform counts"`
+	Sources       []string     `json:"sources,omitempty" description:"Inputs used to compute a Derived value"`
+	Quality       string       `json:"quality,omitempty" description:"Signal quality of a waveform channel"`
+	Trend         *TrendRecord `json:"trend,omitempty" description:"Summary of a Trend interval"`
+}
+
+type TrendRecord struct {
+	Interval string    `json:"interval" description:"Length of the interval (ex. 1m0s)"`
+	Start    time.Time `json:"start" description:"Start of the interval"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	Mean     float64   `json:"mean"`
+	Median   float64   `json:"median"`
+	Last     float64   `json:"last"`
+	Count    int       `json:"count" description:"Number of valid values, min to last are meaningless when 0"`
+	Invalid  int       `json:"invalid" description:"Number of unreadable or unmeasured values"`
+}
+
+func (d QueueModel) Record() Record {
+	var record = Record{
+		SchemaVersion: SCHEMA_VERSION_2,
+		Session:       d.SESSION,
+		Sequence:      d.SEQUENCE,
+		Timestamp:     d.TIMESTAMP,
+		Type:          d.TYPE,
+		Key:           d.KEY,
+		Identifier:    d.IDENTIFIER,
+		MDCCode:       d.MDC_CODE,
+		MDCRefID:      d.MDC_REFID,
+		Device:        "Hamilton",
+		UDID:          d.UDID,
+		Host:          d.HOST,
+		Port:          d.PORT,
+		PatientID:     d.PATIENT_ID,
+		Unit:          d.VALUE_UNIT,
+		WaveformValue: d.WAVEFORM_VALUE,
+		WaveformRaw:   d.WAVEFORM_RAW,
+		Sources:       d.SOURCES,
+		Quality:       d.QUALITY,
+	}
+
+	// 유효한 값이 없던 Trend 구간은 실제 0과 구분되도록 값을 넣지 않습니다.
+	if d.TYPE != "Waveform" && (d.TREND == nil || d.TREND.COUNT > 0) {
+		var value = d.NUMERIC_VALUE
+		record.NumericValue = &value
+	}
+
+	if d.TREND != nil {
+		record.Trend = &TrendRecord{
+			Interval: d.TREND.INTERVAL,
+			Start:    d.TREND.START,
+			Min:      d.TREND.MIN,
+			Max:      d.TREND.MAX,
+			Mean:     d.TREND.MEAN,
+			Median:   d.TREND.MEDIAN,
+			Last:     d.TREND.LAST,
+			Count:    d.TREND.COUNT,
+			Invalid:  d.TREND.INVALID,
+		}
+	}
+
+	return record
+}
+
+// 내용을 version의 형태로 JSON 마샬링합니다. 0은 버전 1로 봅니다.
+func Encode(d QueueModel, version int) ([]byte, error) {
+	switch version {
+	case 0, SCHEMA_VERSION_1:
+		return d.MarshalJSON()
+	case SCHEMA_VERSION_2:
+		return json.Marshal(d.Record())
+	}
+
+	return nil, fmt.Errorf("Unknown Schema Version %d", version)
+}
+
+// version 형태의 JSON Schema (draft 2020-12)를 Go 타입에서 만듭니다.
+func JSONSchema(version int) ([]byte, error) {
+	var root map[string]interface{}
+	switch version {
+	case SCHEMA_VERSION_1:
+		root = schemaOf(reflect.TypeOf(QueueModel{}))
+		root["title"] = "Biosignal QueueModel v1"
+		root["properties"].(map[string]interface{})["TIMESTAMP"] = map[string]interface{}{
+			"type": "string", "format": "date-time", "description": "Time of measurement in RFC3339, truncated to seconds",
+		}
+	case SCHEMA_VERSION_2:
+		root = schemaOf(reflect.TypeOf(Record{}))
+		root["title"] = "Biosignal Record v2"
+	default:
+		return nil, fmt.Errorf("Unknown Schema Version %d", version)
+	}
+
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = fmt.Sprintf("urn:biosignal-hamilton-interface:record:v%d", version)
+	return json.MarshalIndent(root, "", "  ")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaOf(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Ptr:
+		return schemaOf(t.Elem())
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() != reflect.Struct:
+		return map[string]interface{}{}
+	}
+
+	var properties = map[string]interface{}{}
+	var required = []string{}
+	for i := 0; i < t.NumField(); i++ {
+		var field = t.Field(i)
+		var tag = strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		var name = tag[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		var property = schemaOf(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			property["description"] = description
+		}
+
+		properties[name] = property
+		if !strings.Contains(field.Tag.Get("json"), "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
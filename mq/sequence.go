@@ -0,0 +1,35 @@
+package mq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// 실행할 때마다 새로운 Session을 만들고, 기기(UDID)마다 Sequence를 붙입니다.
+// 소비자는 Session과 Sequence로 빠지거나 중복된 메시지를 찾을 수 있습니다.
+type Sequencer struct {
+	Session string
+
+	lock sync.Mutex
+	next map[string]uint64
+}
+
+func NewSequencer() *Sequencer {
+	var b = make([]byte, 8)
+	rand.Read(b)
+
+	return &Sequencer{
+		Session: hex.EncodeToString(b),
+		next:    map[string]uint64{},
+	}
+}
+
+func (sequencer *Sequencer) Stamp(d *QueueModel) {
+	sequencer.lock.Lock()
+	defer sequencer.lock.Unlock()
+
+	sequencer.next[d.UDID]++
+	d.SESSION = sequencer.Session
+	d.SEQUENCE = sequencer.next[d.UDID]
+}
@@ -0,0 +1,216 @@
+package mq
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	SIGN_HMAC_SHA256 = "hmac-sha256"
+	SIGN_ED25519     = "ed25519"
+)
+
+// 서명된 메시지의 첫 줄: "sig:<알고리즘>:<Key ID>:<base64 서명>\n"
+// 서명은 첫 줄 이후의 내용(Content-Type이 있으면 Content-Type 포함) 전체에 대한 것입니다.
+const signaturePrefix = "sig:"
+
+var (
+	ErrUnsigned     = errors.New("Message is Not Signed")
+	ErrUnknownKey   = errors.New("Unknown Signing Key")
+	ErrBadSignature = errors.New("Signature Does Not Match")
+)
+
+// 보내는 메시지에 서명하는 키
+type Signer interface {
+	Algorithm() string
+	KeyID() string
+	Sign(message []byte) []byte
+}
+
+type HMACSigner struct {
+	ID  string
+	Key []byte
+}
+
+func (signer HMACSigner) Algorithm() string { return SIGN_HMAC_SHA256 }
+func (signer HMACSigner) KeyID() string     { return signer.ID }
+
+func (signer HMACSigner) Sign(message []byte) []byte {
+	var mac = hmac.New(sha256.New, signer.Key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+type Ed25519Signer struct {
+	ID  string
+	Key ed25519.PrivateKey
+}
+
+func (signer Ed25519Signer) Algorithm() string { return SIGN_ED25519 }
+func (signer Ed25519Signer) KeyID() string     { return signer.ID }
+
+func (signer Ed25519Signer) Sign(message []byte) []byte {
+	return ed25519.Sign(signer.Key, message)
+}
+
+// 메시지 앞에 서명 줄을 붙입니다.
+func Sign(signer Signer, message []byte) []byte {
+	var line = fmt.Sprintf("%s%s:%s:%s\n", signaturePrefix, signer.Algorithm(), signer.KeyID(),
+		base64.StdEncoding.EncodeToString(signer.Sign(message)))
+
+	return append([]byte(line), message...)
+}
+
+// 서명을 검증할 키, HMAC은 비밀값, Ed25519는 공개키입니다.
+type VerifyKey struct {
+	Algorithm string
+	Key       []byte
+}
+
+// Key ID별 검증 키
+type KeyRing map[string]VerifyKey
+
+// 서명을 검증하고 서명 줄을 뗀 메시지(Unwrap할 수 있는 형태)와 Key ID를 반환합니다.
+func (ring KeyRing) Verify(message []byte) (payload []byte, keyID string, err error) {
+	if !bytes.HasPrefix(message, []byte(signaturePrefix)) {
+		return nil, "", ErrUnsigned
+	}
+
+	var index = bytes.IndexByte(message, '\n')
+	if index < 0 {
+		return nil, "", ErrUnsigned
+	}
+
+	var fields = strings.Split(string(message[len(signaturePrefix):index]), ":")
+	if len(fields) != 3 {
+		return nil, "", fmt.Errorf("Invalid Signature Line: %w", ErrUnsigned)
+	}
+
+	var algorithm = fields[0]
+	keyID = fields[1]
+	payload = message[index+1:]
+
+	signature, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return nil, keyID, fmt.Errorf("%v: %w", err, ErrBadSignature)
+	}
+
+	key, ok := ring[keyID]
+	if !ok || key.Algorithm != algorithm {
+		return nil, keyID, fmt.Errorf("%s (%s): %w", keyID, algorithm, ErrUnknownKey)
+	}
+
+	var valid = false
+	switch algorithm {
+	case SIGN_HMAC_SHA256:
+		valid = hmac.Equal(signature, HMACSigner{Key: key.Key}.Sign(payload))
+	case SIGN_ED25519:
+		valid = len(key.Key) == ed25519.PublicKeySize && ed25519.Verify(ed25519.PublicKey(key.Key), payload, signature)
+	}
+
+	if !valid {
+		return nil, keyID, ErrBadSignature
+	}
+
+	return payload, keyID, nil
+}
+
+func checkKeyID(keyID string) error {
+	if keyID == "" || strings.ContainsAny(keyID, ":\n") {
+		return fmt.Errorf("Invalid Key ID %q", keyID)
+	}
+
+	return nil
+}
+
+func readPEM(path string, kind string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != kind {
+		return nil, fmt.Errorf("No %s in %q", kind, path)
+	}
+
+	return block.Bytes, nil
+}
+
+// 파일에서 서명 키를 읽습니다. HMAC은 비밀값 파일(앞뒤 공백 제외), Ed25519는 PKCS#8 PEM 개인키입니다.
+func LoadSigner(algorithm string, keyID string, path string) (Signer, error) {
+	if err := checkKeyID(keyID); err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case SIGN_HMAC_SHA256:
+		secret, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		secret = bytes.TrimSpace(secret)
+		if len(secret) < 32 {
+			return nil, errors.New("HMAC Secret Should be at Least 32 Bytes")
+		}
+
+		return HMACSigner{ID: keyID, Key: secret}, nil
+	case SIGN_ED25519:
+		der, err := readPEM(path, "PRIVATE KEY")
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+
+		private, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%q is Not an Ed25519 Key", path)
+		}
+
+		return Ed25519Signer{ID: keyID, Key: private}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown Signing Algorithm %q", algorithm)
+}
+
+// 파일에서 검증 키를 읽습니다. HMAC은 비밀값 파일, Ed25519는 PKIX PEM 공개키입니다.
+func LoadVerifyKey(algorithm string, path string) (VerifyKey, error) {
+	switch algorithm {
+	case SIGN_HMAC_SHA256:
+		secret, err := ioutil.ReadFile(path)
+		return VerifyKey{Algorithm: algorithm, Key: bytes.TrimSpace(secret)}, err
+	case SIGN_ED25519:
+		der, err := readPEM(path, "PUBLIC KEY")
+		if err != nil {
+			return VerifyKey{}, err
+		}
+
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return VerifyKey{}, err
+		}
+
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return VerifyKey{}, fmt.Errorf("%q is Not an Ed25519 Key", path)
+		}
+
+		return VerifyKey{Algorithm: algorithm, Key: public}, nil
+	}
+
+	return VerifyKey{}, fmt.Errorf("Unknown Signing Algorithm %q", algorithm)
+}
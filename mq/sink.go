@@ -0,0 +1,80 @@
+package mq
+
+import (
+	"time"
+
+	"github.com/bitly/go-nsq"
+)
+
+// QueueModel을 내보내는 출력 (NSQ, HL7 등)
+type Sink interface {
+	Send(d QueueModel) error
+}
+
+// Address의 NSQ에 Topic으로 보내는 출력
+// Encoder가 없으면 SchemaVersion의 JSON으로 보내며, SchemaVersion이 0이면 버전 1의 형태로 보냅니다.
+// Config가 없으면 평문 TCP로 연결합니다. (ProducerConfig.NSQConfig 참고)
+// Signer가 있으면 메시지마다 서명 줄을 붙입니다.
+// Router가 있으면 Topic 대신 Router가 정한 토픽들로 보냅니다.
+// Pool이 있으면 Address와 Config 대신 Pool의 nsqd들로 보냅니다.
+// Defer가 있으면 DPUB로 보내서 Defer가 지난 뒤에 소비자에게 전달됩니다.
+type NSQSink struct {
+	Address       string
+	Pool          *BrokerPool
+	Topic         string
+	Router        *Router
+	SchemaVersion int
+	Encoder       Encoder
+	Config        *nsq.Config
+	Signer        Signer
+	Defer         time.Duration
+}
+
+func (sink NSQSink) Send(d QueueModel) error {
+	topics, message, err := sink.Message(d)
+	if err != nil {
+		return err
+	}
+
+	if sink.Pool != nil {
+		if sink.Defer > 0 {
+			return sink.Pool.DeferredPublish(topics, sink.Defer, message)
+		}
+
+		return sink.Pool.Publish(topics, message)
+	}
+
+	var config = sink.Config
+	if config == nil {
+		config = nsq.NewConfig()
+	}
+
+	return publish(config, sink.Address, topics, message, sink.Defer)
+}
+
+// d를 보낼 토픽들과 인코딩, 서명한 NSQ 메시지
+func (sink NSQSink) Message(d QueueModel) (topics []string, message []byte, err error) {
+	topics = []string{sink.Topic}
+	if sink.Router != nil {
+		if topics, err = sink.Router.Topics(d); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var encoder = sink.Encoder
+	if encoder == nil {
+		encoder = JSONEncoder{Version: sink.SchemaVersion}
+	}
+
+	body, err := encoder.Encode(d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	message = Wrap(encoder.ContentType(), body)
+	if sink.Signer != nil {
+		message = Sign(sink.Signer, message)
+	}
+
+	return topics, message, nil
+}
@@ -0,0 +1,93 @@
+package packet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Waveform 채널의 12bit 값을 물리 단위로 바꾸기 위한 보정값
+// 물리값 = (12bit 값 - Offset) * Gain
+type Calibration struct {
+	Key    string
+	Unit   string
+	Gain   float64
+	Offset float64
+}
+
+// 채널별 기본 보정값 (Ref. 2.5.1 ~ 2.5.2)
+// 장비 설정에 따라 다를 수 있으므로 실행할 때 덮어쓸 수 있습니다.
+var Calibrations = map[string]Calibration{
+	"P_PATIENT":  {Key: "P_PATIENT", Unit: "cmH2O", Gain: 0.1, Offset: WaveformOffset},
+	"P_OPTIONAL": {Key: "P_OPTIONAL", Unit: "cmH2O", Gain: 0.1, Offset: WaveformOffset},
+	"FLOW":       {Key: "FLOW", Unit: "L/min", Gain: 0.1, Offset: WaveformOffset},
+	"VOLUME":     {Key: "VOLUME", Unit: "mL", Gain: 1, Offset: WaveformOffset},
+	"PCO2":       {Key: "PCO2", Unit: "mmHg", Gain: 0.1, Offset: WaveformOffset},
+}
+
+func (calibration Calibration) Physical(count uint16) float64 {
+	return (float64(count) - calibration.Offset) * calibration.Gain
+}
+
+// "GAIN:OFFSET" 형태의 문자열로 Key 채널의 보정값을 덮어씁니다.
+func SetCalibration(key string, value string) error {
+	calibration, ok := Calibrations[strings.ToUpper(key)]
+	if !ok {
+		return fmt.Errorf("Unknown Waveform Channel %q", key)
+	}
+
+	var parts = strings.Split(value, ":")
+	if len(parts) != 2 {
+		return fmt.Errorf("Invalid Calibration %q, use GAIN:OFFSET", value)
+	}
+
+	gain, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return err
+	}
+
+	offset, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return err
+	}
+
+	calibration.Gain = gain
+	calibration.Offset = offset
+	Calibrations[calibration.Key] = calibration
+	return nil
+}
+
+// 하나의 Waveform 채널 값
+type ChannelSample struct {
+	Calibration
+	Count uint16
+}
+
+func (sample ChannelSample) Value() float64 {
+	return sample.Physical(sample.Count)
+}
+
+func newChannelSample(key string, value WaveformValue) ChannelSample {
+	return ChannelSample{
+		Calibration: Calibrations[key],
+		Count:       Decode12Bit(value.High, value.Low),
+	}
+}
+
+func (response OnlineValues34) Samples() []ChannelSample {
+	return []ChannelSample{
+		newChannelSample("P_PATIENT", response.PPatient),
+		newChannelSample("FLOW", response.Flow),
+		newChannelSample("VOLUME", response.Volume),
+		newChannelSample("PCO2", response.PCO2),
+	}
+}
+
+func (response OnlineValues120) Samples() []ChannelSample {
+	return []ChannelSample{
+		newChannelSample("P_PATIENT", response.PPatient),
+		newChannelSample("P_OPTIONAL", response.POptional),
+		newChannelSample("FLOW", response.Flow),
+		newChannelSample("VOLUME", response.Volume),
+	}
+}
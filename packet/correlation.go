@@ -0,0 +1,237 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// 요청한 패킷과 맞지 않는 응답이 왔을 때 반환되는 에러
+type ProtocolError struct {
+	Request  RequestPacket
+	Expected string
+	Raw      []byte
+	Stale    bool  // 이전 요청에 대한 늦은 응답
+	Err      error // ErrIdentifierMismatch, ErrStaleReply, *FrameError 등
+}
+
+func (err *ProtocolError) Error() string {
+	return fmt.Sprintf("%v: request %d expects %s, got % X", err.Err, err.Request.Identifier, err.Expected, err.Raw)
+}
+
+func (err *ProtocolError) Unwrap() error {
+	return err.Err
+}
+
+// 요청한 Identifier에 대해 장비가 줄 수 있는 응답 포맷과 길이 (Ref. 2.3 ~ 2.5.2)
+func ExpectedFormats(request RequestPacket) map[int]int {
+	var identifier = int(request.Identifier)
+
+	switch {
+	case identifier == 34:
+		return map[int]int{RESP_TYPE_C_34: 13}
+	case identifier == 120:
+		return map[int]int{RESP_TYPE_C_120: 13}
+	case identifier == 0x41 || identifier == 0x42 || identifier == 0x43:
+		// I:E Ratio, P max, P mean은 Numeric으로 요청하므로 Type A를 받고,
+		// 길이가 같은 Type B Format 2 대신 Format 3만 받습니다.
+		return map[int]int{RESP_TYPE_A: 9, RESP_TYPE_B_FORMAT_3: 8}
+	case identifier == 0x52 || identifier == 0x56:
+		return map[int]int{RESP_TYPE_B_FORMAT_2: 9, RESP_TYPE_B_FORMAT_3: 8}
+	case identifier >= 124 && identifier <= 127:
+		return map[int]int{RESP_TYPE_B_FORMAT_1: 9}
+	case (identifier >= 30 && identifier <= 33) || (identifier >= 35 && identifier <= 119) ||
+		(identifier >= 121 && identifier <= 123):
+		return map[int]int{RESP_TYPE_A: 9}
+	}
+
+	return map[int]int{}
+}
+
+func describeFormats(formats map[int]int) string {
+	var names = map[int]string{
+		RESP_TYPE_A:          "Type A",
+		RESP_TYPE_B_FORMAT_1: "Type B Format 1",
+		RESP_TYPE_B_FORMAT_2: "Type B Format 2",
+		RESP_TYPE_B_FORMAT_3: "Type B Format 3",
+		RESP_TYPE_C_34:       "Type C 34",
+		RESP_TYPE_C_120:      "Type C 120",
+	}
+
+	var result = ""
+	for _, format := range []int{
+		RESP_TYPE_A, RESP_TYPE_B_FORMAT_1, RESP_TYPE_B_FORMAT_2, RESP_TYPE_B_FORMAT_3, RESP_TYPE_C_34, RESP_TYPE_C_120,
+	} {
+		if length, ok := formats[format]; ok {
+			if result != "" {
+				result += " or "
+			}
+
+			result += fmt.Sprintf("%s (%d bytes)", names[format], length)
+		}
+	}
+
+	if result == "" {
+		return "nothing"
+	}
+
+	return result
+}
+
+// 보낸 요청을 기준으로 응답을 해석합니다.
+// ParseResponse와 달리 길이만 보고 포맷을 추측하지 않고, 응답의 Identifier가 요청과 같은지 검사합니다.
+func ParseResponseFor(request RequestPacket, raw []byte) (result Response, err error) {
+	var r_error = []byte{
+		0x02, 0x52, 0x45, 0x52, 0x52, 0x4F, 0x52, 0x03, 0x0D,
+	}
+
+	var formats = ExpectedFormats(request)
+	var expected = fmt.Sprintf("%s with identifier %d", describeFormats(formats), request.Identifier)
+
+	// 장비가 요청을 처리하지 못한 경우에도 응답은 돌려줍니다.
+	if reflect.DeepEqual(r_error, raw) {
+		return ErrorResponse{}, &ProtocolError{Request: request, Expected: expected, Raw: raw, Err: ErrDeviceRERROR}
+	}
+
+	if len(formats) == 0 {
+		return nil, &ProtocolError{Request: request, Expected: expected, Raw: raw,
+			Err: &FrameError{Err: ErrUnknownIdentifier, Offset: 1, Bytes: request.ToBytes()}}
+	}
+
+	if err := checkFrame(raw); err != nil {
+		return nil, &ProtocolError{Request: request, Expected: expected, Raw: raw, Err: err}
+	}
+
+	if raw[1] != request.Identifier {
+		return nil, &ProtocolError{Request: request, Expected: expected, Raw: raw, Err: ErrIdentifierMismatch}
+	}
+
+	for format, length := range formats {
+		if len(raw) != length {
+			continue
+		}
+
+		switch format {
+		case RESP_TYPE_A:
+			return NumericResponse{
+				Identifier: raw[1],
+				Values:     raw[2:7],
+			}, nil
+		case RESP_TYPE_B_FORMAT_1:
+			return IdentityResponse{
+				Format:           RESP_TYPE_B_FORMAT_1,
+				Identifier:       raw[1],
+				DeviceIdentifier: []byte{raw[2]},
+				Values:           raw[3:7],
+			}, nil
+		case RESP_TYPE_B_FORMAT_2:
+			return IdentityResponse{
+				Format:           RESP_TYPE_B_FORMAT_2,
+				DeviceIdentifier: raw[1:3],
+				Values:           raw[3:7],
+			}, nil
+		case RESP_TYPE_B_FORMAT_3:
+			return IdentityResponse{
+				Format:           RESP_TYPE_B_FORMAT_3,
+				DeviceIdentifier: []byte{raw[1]},
+				Values:           raw[2:6],
+			}, nil
+		case RESP_TYPE_C_34:
+			return OnlineValues34{
+				Identifier:       raw[1],
+				VentilatorStatus: raw[2],
+				PPatient:         WaveformValue{Low: raw[3], High: raw[4]},
+				Flow:             WaveformValue{Low: raw[5], High: raw[6]},
+				Volume:           WaveformValue{Low: raw[7], High: raw[8]},
+				PCO2:             WaveformValue{Low: raw[9], High: raw[10]},
+			}, nil
+		case RESP_TYPE_C_120:
+			return OnlineValues120{
+				Identifier:       raw[1],
+				VentilatorStatus: raw[2],
+				PPatient:         WaveformValue{Low: raw[3], High: raw[4]},
+				POptional:        WaveformValue{Low: raw[5], High: raw[6]},
+				Flow:             WaveformValue{Low: raw[7], High: raw[8]},
+				Volume:           WaveformValue{Low: raw[9], High: raw[10]},
+			}, nil
+		}
+	}
+
+	return nil, &ProtocolError{Request: request, Expected: expected, Raw: raw,
+		Err: &FrameError{Err: ErrBadLength, Offset: len(raw), Bytes: raw}}
+}
+
+// 보낸 요청을 기억해두고 들어온 응답과 짝을 맞춥니다.
+// 직전 요청에 대한 늦은 응답이 들어오면 Stale이 설정된 ProtocolError를 반환합니다.
+type Correlator struct {
+	current  *RequestPacket
+	previous *RequestPacket
+}
+
+// 요청을 기록하고 장비에 보낼 바이트를 반환합니다.
+func (correlator *Correlator) Send(request RequestPacket) []byte {
+	correlator.previous = correlator.current
+	correlator.current = &request
+
+	return request.ToBytes()
+}
+
+// 마지막으로 보낸 요청
+func (correlator *Correlator) Current() RequestPacket {
+	if correlator.current == nil {
+		return RequestPacket{}
+	}
+
+	return *correlator.current
+}
+
+// 늦은 응답을 몇 번까지 버리고 다시 읽을지
+const MaxStaleReplies = 3
+
+func (correlator *Correlator) Receive(raw []byte) (result Response, err error) {
+	if correlator.current == nil {
+		return nil, &ProtocolError{Expected: "no reply", Raw: raw, Err: ErrUnsolicitedReply}
+	}
+
+	// 늦은 응답과 현재 응답이 한 번에 읽힌 경우 늦은 응답을 떼어냅니다.
+	if correlator.previous != nil && correlator.previous.Identifier != correlator.current.Identifier {
+		for _, length := range ExpectedFormats(*correlator.previous) {
+			if len(raw) <= length {
+				continue
+			}
+
+			if _, err := ParseResponseFor(*correlator.previous, raw[:length]); err == nil {
+				if result, err := ParseResponseFor(*correlator.current, raw[length:]); err == nil {
+					return result, nil
+				}
+			}
+		}
+	}
+
+	result, err = ParseResponseFor(*correlator.current, raw)
+	if protocolError, ok := err.(*ProtocolError); ok && protocolError.Err == ErrIdentifierMismatch &&
+		correlator.previous != nil && correlator.previous.Identifier != correlator.current.Identifier {
+		if _, err := ParseResponseFor(*correlator.previous, raw); err == nil {
+			protocolError.Stale = true
+			protocolError.Err = ErrStaleReply
+		}
+	}
+
+	return result, err
+}
+
+// read로 응답을 읽어 짝을 맞춥니다.
+// 늦은 응답이면 버리고, 현재 요청의 응답이 버퍼에 남아있으므로 MaxStaleReplies번까지 다시 읽습니다.
+func (correlator *Correlator) ReceiveFrom(read func() ([]byte, error)) (result Response, err error) {
+	for attempt := 0; ; attempt++ {
+		raw, err := read()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = correlator.Receive(raw)
+		if !errors.Is(err, ErrStaleReply) || attempt >= MaxStaleReplies {
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package packet
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errors.Is로 비교할 수 있는 에러 목록
+var (
+	ErrShortFrame          = errors.New("Short Frame")
+	ErrBadLength           = errors.New("Invalid Packet Length")
+	ErrBadSTX              = errors.New("Missing STX (0x02)")
+	ErrBadTerminator       = errors.New("Missing Terminator (0x03 0x0D)")
+	ErrUnknownIdentifier   = errors.New("Unknown Identifier")
+	ErrAmbiguousIdentifier = errors.New("Ambiguous Identifier Name")
+	ErrDeviceRERROR        = errors.New("Device Replied RERROR")
+	ErrIdentifierMismatch  = errors.New("Identifier Mismatch")
+	ErrStaleReply          = errors.New("Stale Reply")
+	ErrUnsolicitedReply    = errors.New("Unsolicited Reply")
+)
+
+// 패킷의 어느 위치(Offset)가 잘못되었는지 담고 있는 에러
+// errors.As로 꺼내서 사용하고, errors.Is로 원인(Err)과 비교할 수 있습니다.
+type FrameError struct {
+	Err    error
+	Offset int
+	Bytes  []byte
+}
+
+func (err *FrameError) Error() string {
+	return fmt.Sprintf("%v at offset %d: % X", err.Err, err.Offset, err.Bytes)
+}
+
+func (err *FrameError) Unwrap() error {
+	return err.Err
+}
+
+// STX(0x02)로 시작해서 ETX(0x03), CR(0x0D)로 끝나는지 검사합니다. (Ref. 2.2)
+func checkFrame(raw []byte) error {
+	if len(raw) < 4 {
+		return &FrameError{Err: ErrShortFrame, Offset: len(raw), Bytes: raw}
+	}
+
+	if raw[0] != 0x02 {
+		return &FrameError{Err: ErrBadSTX, Offset: 0, Bytes: raw}
+	}
+
+	if raw[len(raw)-2] != 0x03 || raw[len(raw)-1] != 0x0D {
+		return &FrameError{Err: ErrBadTerminator, Offset: len(raw) - 2, Bytes: raw}
+	}
+
+	return nil
+}
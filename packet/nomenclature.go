@@ -0,0 +1,162 @@
+package packet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IEEE 11073-10101 명명법의 코드
+// Code는 (Partition << 16) + Term Code 형태의 숫자 코드입니다. (ex. MDC_PART_SCADA는 2 << 16 = 131072)
+type Nomenclature struct {
+	Code  int
+	RefID string
+}
+
+// Numeric 측정값(Hamilton Identifier)의 MDC 코드, 실행할 때 덮어쓰거나 추가할 수 있습니다.
+var Nomenclatures = map[int]Nomenclature{
+	35:  {Code: 151708, RefID: "MDC_AWAY_CO2_ET"},
+	36:  {Code: 150456, RefID: "MDC_PULS_OXIM_SAT_O2"},
+	37:  {Code: 149530, RefID: "MDC_PULS_OXIM_PULS_RATE"},
+	40:  {Code: 184352, RefID: "MDC_VENT_MODE"},
+	60:  {Code: 151876, RefID: "MDC_VOL_AWAY_TIDAL_INSP"},
+	61:  {Code: 151872, RefID: "MDC_VOL_AWAY_TIDAL_EXP"},
+	62:  {Code: 151880, RefID: "MDC_VOL_MINUTE_AWAY"},
+	63:  {Code: 151562, RefID: "MDC_RESP_RATE"},
+	64:  {Code: 151594, RefID: "MDC_VENT_RESP_RATE_SPONT"},
+	65:  {Code: 151832, RefID: "MDC_RATIO_IE"},
+	66:  {Code: 151797, RefID: "MDC_PRESS_AWAY_INSP_MAX"},
+	67:  {Code: 151799, RefID: "MDC_PRESS_AWAY_INSP_MEAN"},
+	68:  {Code: 151976, RefID: "MDC_PRESS_AWAY_END_EXP_POS"},
+	69:  {Code: 151784, RefID: "MDC_PRESS_RESP_PLAT"},
+	70:  {Code: 152612, RefID: "MDC_TIME_PD_EXP"},
+	71:  {Code: 152196, RefID: "MDC_CONC_AWAY_O2_INSP"},
+	72:  {Code: 151844, RefID: "MDC_RES_AWAY_INSP"},
+	73:  {Code: 151848, RefID: "MDC_RES_AWAY_EXP"},
+	74:  {Code: 151688, RefID: "MDC_COMPL_LUNG"},
+	75:  {Code: 151773, RefID: "MDC_FLOW_AWAY_INSP_MAX"},
+	103: {Code: 151980, RefID: "MDC_PRESS_AWAY_END_EXP_POS_INTRINSIC"},
+	110: {Code: 188736, RefID: "MDC_MASS_BODY_ACTUAL"},
+	112: {Code: 151794, RefID: "MDC_PRESS_AWAY_MIN"},
+	113: {Code: 152608, RefID: "MDC_TIME_PD_INSP"},
+	116: {Code: 151769, RefID: "MDC_FLOW_AWAY_EXP_MAX"},
+	122: {Code: 151796, RefID: "MDC_PRESS_AWAY_INSP"},
+}
+
+// Nomenclatures에 넣지 않은 이유
+const (
+	reasonSetting    = "Setting, sharing the RefID of the measurement would make them indistinguishable"
+	reasonAlarmLimit = "Alarm limit, a setting without its own term"
+	reasonAlarm      = "Alarm state, published as the Alarm type"
+	reasonClock      = "Device clock, not a measurement"
+	reasonDevice     = "Device information, not a measurement"
+	reasonHamilton   = "Hamilton proprietary index without a term"
+	reasonBreathType = "No term splits tidal volume by mandatory and spontaneous breaths"
+)
+
+// Nomenclatures에 없는 Numeric 값(Type A)의 Identifier와 그 이유, MDC_CODE와 MDC_REFID 없이 보냅니다.
+var UnmappedIdentifiers = map[int]string{
+	31:  reasonSetting,
+	41:  reasonSetting,
+	42:  reasonSetting,
+	43:  reasonSetting,
+	44:  reasonSetting,
+	45:  reasonSetting,
+	46:  reasonSetting,
+	47:  reasonSetting,
+	48:  reasonSetting,
+	49:  reasonSetting,
+	50:  reasonSetting,
+	51:  reasonSetting,
+	87:  reasonSetting,
+	104: reasonSetting,
+	105: reasonSetting,
+	106: reasonSetting,
+	107: reasonSetting,
+	108: reasonSetting,
+	109: reasonSetting,
+	111: reasonSetting,
+	52:  reasonAlarmLimit,
+	53:  reasonAlarmLimit,
+	54:  reasonAlarmLimit,
+	55:  reasonAlarmLimit,
+	56:  reasonAlarmLimit,
+	57:  reasonAlarmLimit,
+	80:  reasonClock,
+	81:  reasonClock,
+	83:  reasonClock,
+	84:  reasonClock,
+	85:  reasonClock,
+	88:  reasonAlarm,
+	89:  "Alarm silence state, not a measurement",
+	90:  reasonAlarm,
+	91:  reasonAlarm,
+	92:  reasonAlarm,
+	93:  reasonAlarm,
+	94:  reasonAlarm,
+	95:  reasonAlarm,
+	96:  reasonAlarm,
+	97:  reasonAlarm,
+	98:  reasonAlarm,
+	99:  reasonAlarm,
+	100: reasonAlarm,
+	101: reasonAlarm,
+	102: reasonAlarm,
+	123: reasonDevice,
+	38:  reasonHamilton + " (Heart-Lung Interaction)",
+	39:  reasonHamilton + " (Variability Index of SpO2 sensor)",
+	76:  reasonBreathType,
+	77:  reasonBreathType,
+	78:  reasonBreathType,
+	79:  reasonBreathType,
+	114: reasonHamilton + " (Leak percentage of tidal volume)",
+	115: "No term for airway occlusion pressure at 100 ms (P0.1)",
+	117: reasonHamilton + " (Expiratory time constant)",
+	118: reasonHamilton + " (Inspiratory time constant)",
+	119: reasonHamilton + " (Imposed work of breathing)",
+	121: reasonHamilton + " (Pressure time product)",
+}
+
+// Waveform 채널의 MDC 코드
+var ChannelNomenclatures = map[string]Nomenclature{
+	"P_PATIENT": {Code: 151792, RefID: "MDC_PRESS_AWAY"},
+	"FLOW":      {Code: 151764, RefID: "MDC_FLOW_AWAY"},
+	"VOLUME":    {Code: 151864, RefID: "MDC_VOL_AWAY"},
+	"PCO2":      {Code: 151704, RefID: "MDC_AWAY_CO2"},
+}
+
+// ChannelNomenclatures에 없는 Waveform 채널과 그 이유
+var UnmappedChannels = map[string]string{
+	"P_OPTIONAL": "Auxiliary pressure input, the term depends on the connected sensor (ex. esophageal, tracheal), set it with --mdc",
+}
+
+// "CODE:REFID" 형태의 문자열로 명명법을 덮어씁니다.
+// key는 Hamilton Identifier(ex. 68)나 Waveform 채널 이름(ex. P_OPTIONAL)입니다.
+func SetNomenclature(key string, value string) error {
+	var parts = strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "MDC_") {
+		return fmt.Errorf("Invalid Nomenclature %q, use CODE:REFID", value)
+	}
+
+	code, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return err
+	}
+
+	var nomenclature = Nomenclature{Code: code, RefID: parts[1]}
+	if identifier, err := strconv.Atoi(key); err == nil {
+		if _, ok := TypeIntString[identifier]; !ok {
+			return fmt.Errorf("Unknown Identifier %d", identifier)
+		}
+
+		Nomenclatures[identifier] = nomenclature
+		return nil
+	}
+
+	if _, ok := Calibrations[strings.ToUpper(key)]; !ok {
+		return fmt.Errorf("Unknown Waveform Channel %q", key)
+	}
+
+	ChannelNomenclatures[strings.ToUpper(key)] = nomenclature
+	return nil
+}
@@ -94,6 +94,77 @@ var TypeIntString = map[int]string{
 	125: "Software Version (SW version)",
 }
 
+// Numeric 값의 단위 (단위가 없는 Identifier는 생략)
+var TypeUnit = map[int]string{
+	41:  "b/min",
+	42:  "b/min",
+	43:  "ml",
+	44:  "s",
+	45:  "s",
+	47:  "cmH2O",
+	48:  "cmH2O",
+	49:  "cmH2O",
+	50:  "%",
+	51:  "l/min",
+	87:  "cmH2O",
+	104: "l/min",
+	106: "l/min",
+	107: "cmH2O",
+	108: "%",
+	109: "ms",
+	110: "kg",
+	111: "%",
+	52:  "b/min",
+	53:  "cmH2O",
+	54:  "l/min",
+	55:  "l/min",
+	56:  "%",
+	57:  "%",
+	35:  "mmHg",
+	36:  "%",
+	37:  "1/min",
+	60:  "ml",
+	61:  "ml",
+	62:  "l/min",
+	63:  "b/min",
+	64:  "b/min",
+	66:  "cmH2O",
+	67:  "cmH2O",
+	68:  "cmH2O",
+	69:  "cmH2O",
+	70:  "s",
+	71:  "%",
+	72:  "cmH2O/l/s",
+	73:  "cmH2O/l/s",
+	74:  "ml/cmH2O",
+	75:  "l/min",
+	76:  "ml",
+	77:  "ml",
+	78:  "ml",
+	79:  "ml",
+	103: "cmH2O",
+	112: "cmH2O",
+	113: "s",
+	114: "ml",
+	115: "cmH2O",
+	116: "l/min",
+	117: "s",
+	118: "s",
+	119: "J/l",
+	121: "cmH2O*s",
+	122: "cmH2O",
+}
+
+// 알람 상태의 Identifier 범위 (SpezAlarm ~ Gas Supply)
+const (
+	ALARM_FIRST = 88
+	ALARM_LAST  = 102
+)
+
+func IsAlarm(identifier int) bool {
+	return identifier >= ALARM_FIRST && identifier <= ALARM_LAST
+}
+
 const (
 	RESP_TYPE_RERROR     = iota
 	RESP_TYPE_A          // Ref. 2.3
@@ -1,6 +1,11 @@
 package packet
 
-import "errors"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type RequestPacket struct {
 	Identifier byte
@@ -16,11 +21,43 @@ func (packet RequestPacket) GetType() (result string) {
 }
 
 func ParseRequestPacket(raw []byte) (result RequestPacket, err error) {
+	if err := checkFrame(raw); err != nil {
+		return RequestPacket{}, err
+	}
+
 	if len(raw) != 4 {
-		return RequestPacket{}, errors.New("Invalid Packet Length")
+		return RequestPacket{}, &FrameError{Err: ErrBadLength, Offset: len(raw), Bytes: raw}
 	}
 
 	return RequestPacket{
 		Identifier: raw[1],
 	}, nil
 }
+
+// 숫자 또는 TypeIntString의 이름으로 Identifier를 찾습니다.
+func LookupIdentifier(name string) (result byte, err error) {
+	if number, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+		if number < 0 || number > 0xFF {
+			return 0, fmt.Errorf("%w: %d is out of range", ErrUnknownIdentifier, number)
+		}
+
+		return byte(number), nil
+	}
+
+	var matches = []int{}
+	for identifier, typeName := range TypeIntString {
+		if strings.EqualFold(typeName, strings.TrimSpace(name)) {
+			matches = append(matches, identifier)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownIdentifier, name)
+	case 1:
+		return byte(matches[0]), nil
+	}
+
+	sort.Ints(matches)
+	return 0, fmt.Errorf("%w, use one of %v", ErrAmbiguousIdentifier, matches)
+}
@@ -0,0 +1,266 @@
+package packet
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 패킷 포맷별로 의미있는 필드만 가진 응답 타입들이 구현하는 인터페이스
+// 타입 스위치로 구체 타입을 꺼내서 사용합니다.
+type Response interface {
+	Type() int
+	Packet() ResponsePacket
+	ToBytes() []byte
+}
+
+// RERROR (Ref. 2.3)
+type ErrorResponse struct{}
+
+// Type A (Ref. 2.3)
+type NumericResponse struct {
+	Identifier byte
+	Values     []byte
+}
+
+// Type B Format 1 ~ 3 (Ref. 2.4.2 ~ 2.4.4)
+// Format 2와 3은 Identifier 없이 DeviceIdentifier만 옵니다.
+type IdentityResponse struct {
+	Format           int
+	Identifier       byte
+	DeviceIdentifier []byte
+	Values           []byte
+}
+
+// 12bit 파형 값을 나눠 담은 Low, High 바이트 (각각 하위 6bit만 사용)
+type WaveformValue struct {
+	Low  byte
+	High byte
+}
+
+// Type C, Identifier 34 (Ref. 2.5.1)
+type OnlineValues34 struct {
+	Identifier       byte
+	VentilatorStatus byte
+	PPatient         WaveformValue
+	Flow             WaveformValue
+	Volume           WaveformValue
+	PCO2             WaveformValue
+}
+
+// Type C, Identifier 120 (Ref. 2.5.2)
+type OnlineValues120 struct {
+	Identifier       byte
+	VentilatorStatus byte
+	PPatient         WaveformValue
+	POptional        WaveformValue
+	Flow             WaveformValue
+	Volume           WaveformValue
+}
+
+// OnlineValues120의 VentilatorStatus 비트
+// 둘 다 0이면(대기 중이거나 장비가 알려주지 않는 경우) 호흡 단계를 알 수 없습니다.
+const (
+	STATUS_INSPIRATION byte = 1 << 0
+	STATUS_EXPIRATION  byte = 1 << 1
+)
+
+func (response ErrorResponse) Type() int {
+	return RESP_TYPE_RERROR
+}
+
+func (response ErrorResponse) Packet() ResponsePacket {
+	return ResponsePacket{
+		ResponseType: RESP_TYPE_RERROR,
+	}
+}
+
+func (response ErrorResponse) ToBytes() []byte {
+	return response.Packet().ToBytes()
+}
+
+func (response NumericResponse) Type() int {
+	return RESP_TYPE_A
+}
+
+func (response NumericResponse) Packet() ResponsePacket {
+	return ResponsePacket{
+		ResponseType: RESP_TYPE_A,
+		Identifier:   response.Identifier,
+		Values:       response.Values,
+	}
+}
+
+func (response NumericResponse) ToBytes() []byte {
+	return response.Packet().ToBytes()
+}
+
+func (response NumericResponse) Name() string {
+	return TypeIntString[int(response.Identifier)]
+}
+
+func (response NumericResponse) Unit() string {
+	return TypeUnit[int(response.Identifier)]
+}
+
+// ASCII로 들어온 값을 실수로 변환합니다.
+func (response NumericResponse) Value() (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(string(response.Values)), 64)
+}
+
+func (response IdentityResponse) Type() int {
+	return response.Format
+}
+
+func (response IdentityResponse) Packet() ResponsePacket {
+	return ResponsePacket{
+		ResponseType:     response.Format,
+		Identifier:       response.Identifier,
+		DeviceIdentifier: response.DeviceIdentifier,
+		Values:           response.Values,
+	}
+}
+
+func (response IdentityResponse) ToBytes() []byte {
+	return response.Packet().ToBytes()
+}
+
+// 공백을 제외한 ASCII 값 (ex. 소프트웨어 버전, 벤틸레이터 번호)
+func (response IdentityResponse) Text() string {
+	return strings.TrimSpace(string(response.Values))
+}
+
+// 0 ~ 4095 사이의 12bit 값
+func (value WaveformValue) Count() int {
+	return int(Decode12Bit(value.High, value.Low))
+}
+
+// 2048을 0으로 하는 부호있는 값
+func (value WaveformValue) Signed() int {
+	return DecodeSigned12Bit(value.High, value.Low)
+}
+
+func (response OnlineValues34) Type() int {
+	return RESP_TYPE_C_34
+}
+
+func (response OnlineValues34) Packet() ResponsePacket {
+	return ResponsePacket{
+		ResponseType:     RESP_TYPE_C_34,
+		Identifier:       response.Identifier,
+		VentilatorStatus: response.VentilatorStatus,
+		PPatientLow:      response.PPatient.Low,
+		PPatientHigh:     response.PPatient.High,
+		FlowLow:          response.Flow.Low,
+		FlowHigh:         response.Flow.High,
+		VolumeLow:        response.Volume.Low,
+		VolumeHigh:       response.Volume.High,
+		PCO2Low:          response.PCO2.Low,
+		PCO2High:         response.PCO2.High,
+	}
+}
+
+func (response OnlineValues34) ToBytes() []byte {
+	return response.Packet().ToBytes()
+}
+
+func (response OnlineValues120) Type() int {
+	return RESP_TYPE_C_120
+}
+
+func (response OnlineValues120) Packet() ResponsePacket {
+	return ResponsePacket{
+		ResponseType:     RESP_TYPE_C_120,
+		Identifier:       response.Identifier,
+		VentilatorStatus: response.VentilatorStatus,
+		PPatientLow:      response.PPatient.Low,
+		PPatientHigh:     response.PPatient.High,
+		POptionalLow:     response.POptional.Low,
+		POptionalHigh:    response.POptional.High,
+		FlowLow:          response.Flow.Low,
+		FlowHigh:         response.Flow.High,
+		VolumeLow:        response.Volume.Low,
+		VolumeHigh:       response.Volume.High,
+	}
+}
+
+func (response OnlineValues120) ToBytes() []byte {
+	return response.Packet().ToBytes()
+}
+
+func ParseResponse(raw []byte) (result Response, err error) {
+	var r_error = []byte{
+		0x02, 0x52, 0x45, 0x52, 0x52, 0x4F, 0x52, 0x03, 0x0D,
+	}
+
+	// 0. Check packet is rerror
+	if reflect.DeepEqual(r_error, raw) {
+		return ErrorResponse{}, nil
+	}
+
+	if err := checkFrame(raw); err != nil {
+		return nil, err
+	}
+
+	// 1. check raw packet's length
+	if len(raw) == 8 {
+		return IdentityResponse{
+			Format:           RESP_TYPE_B_FORMAT_3,
+			DeviceIdentifier: []byte{raw[1]},
+			Values:           raw[2:6],
+		}, nil
+	}
+
+	// 2. check parameter identifier
+	if len(raw) == 13 {
+		if int(raw[1]) == 34 {
+			return OnlineValues34{
+				Identifier:       raw[1],
+				VentilatorStatus: raw[2],
+				PPatient:         WaveformValue{Low: raw[3], High: raw[4]},
+				Flow:             WaveformValue{Low: raw[5], High: raw[6]},
+				Volume:           WaveformValue{Low: raw[7], High: raw[8]},
+				PCO2:             WaveformValue{Low: raw[9], High: raw[10]},
+			}, nil
+		}
+
+		return OnlineValues120{
+			Identifier:       raw[1],
+			VentilatorStatus: raw[2],
+			PPatient:         WaveformValue{Low: raw[3], High: raw[4]},
+			POptional:        WaveformValue{Low: raw[5], High: raw[6]},
+			Flow:             WaveformValue{Low: raw[7], High: raw[8]},
+			Volume:           WaveformValue{Low: raw[9], High: raw[10]},
+		}, nil
+	}
+
+	if len(raw) == 9 {
+		var identifier = int(raw[1])
+
+		if identifier == int(0x41) || identifier == int(0x56) || identifier == int(0x42) ||
+			identifier == int(0x52) || identifier == int(0x43) {
+			return IdentityResponse{
+				Format:           RESP_TYPE_B_FORMAT_2,
+				DeviceIdentifier: raw[1:3],
+				Values:           raw[3:7],
+			}, nil
+		} else if (identifier >= 30 && identifier <= 33) || (identifier >= 35 && identifier <= 119) ||
+			(identifier >= 121 && identifier <= 123) {
+			return NumericResponse{
+				Identifier: raw[1],
+				Values:     raw[2:7],
+			}, nil
+		} else if identifier >= 124 && identifier <= 127 {
+			return IdentityResponse{
+				Format:           RESP_TYPE_B_FORMAT_1,
+				Identifier:       raw[1],
+				DeviceIdentifier: []byte{raw[2]},
+				Values:           raw[3:7],
+			}, nil
+		}
+
+		return nil, &FrameError{Err: ErrUnknownIdentifier, Offset: 1, Bytes: raw}
+	}
+
+	return nil, &FrameError{Err: ErrBadLength, Offset: len(raw), Bytes: raw}
+}
@@ -1,9 +1,9 @@
 package packet
 
 import (
-	"errors"
 	"math"
-	"reflect"
+	"strconv"
+	"strings"
 )
 
 type ResponsePacket struct {
@@ -78,103 +78,33 @@ func (packet ResponsePacket) ToBytes() (result []byte) {
 	return r_error
 }
 
+// 호환성을 위해 남겨둔 함수입니다. 새 코드는 ParseResponse를 사용하세요.
 func ParseResponsePacket(raw []byte) (result ResponsePacket, err error) {
-	var r_error = []byte{
-		0x02, 0x52, 0x45, 0x52, 0x52, 0x4F, 0x52, 0x03, 0x0D,
-	}
-
-	// 0. Check packet is rerror
-	if reflect.DeepEqual(r_error, raw) {
-		return ResponsePacket{
-			ResponseType: RESP_TYPE_RERROR,
-		}, nil
+	response, err := ParseResponse(raw)
+	if err != nil {
+		return ResponsePacket{}, err
 	}
 
-	// 1. check raw packet's length
-	if len(raw) == 8 {
-		return ResponsePacket{
-			ResponseType:     RESP_TYPE_B_FORMAT_3,
-			DeviceIdentifier: []byte{raw[1]},
-			Values:           raw[2:6],
-		}, nil
-	}
+	return response.Packet(), nil
+}
 
-	// 2. check parameter identifier
-	if len(raw) == 13 {
-		if int(raw[1]) == 34 {
-			return ResponsePacket{
-				ResponseType:     RESP_TYPE_C_34,
-				Identifier:       raw[1],
-				VentilatorStatus: raw[2],
-				PPatientLow:      raw[3],
-				PPatientHigh:     raw[4],
-				FlowLow:          raw[5],
-				FlowHigh:         raw[6],
-				VolumeLow:        raw[7],
-				VolumeHigh:       raw[8],
-				PCO2Low:          raw[9],
-				PCO2High:         raw[10],
-			}, nil
-		} else {
-			return ResponsePacket{
-				ResponseType:     RESP_TYPE_C_120,
-				Identifier:       raw[1],
-				VentilatorStatus: raw[2],
-				PPatientLow:      raw[3],
-				PPatientHigh:     raw[4],
-				POptionalLow:     raw[5],
-				POptionalHigh:    raw[6],
-				FlowLow:          raw[7],
-				FlowHigh:         raw[8],
-				VolumeLow:        raw[9],
-				VolumeHigh:       raw[10],
-			}, nil
-		}
-	}
+// Type A 패킷의 ASCII 값을 실수로 변환합니다.
+func (packet ResponsePacket) NumericValue() (result float64, err error) {
+	return strconv.ParseFloat(strings.TrimSpace(string(packet.Values)), 64)
+}
 
-	var flag = 0
-	if len(raw) == 9 {
-		var identifier = int(raw[1])
-
-		if identifier == int(0x41) || identifier == int(0x56) || identifier == int(0x42) ||
-			identifier == int(0x52) || identifier == int(0x43) {
-			flag = RESP_TYPE_B_FORMAT_2
-		} else if identifier >= 30 && identifier <= 33 {
-			flag = RESP_TYPE_A
-		} else if identifier >= 35 && identifier <= 119 {
-			flag = RESP_TYPE_A
-		} else if identifier >= 121 && identifier <= 123 {
-			flag = RESP_TYPE_A
-		} else if identifier >= 124 && identifier <= 127 {
-			flag = RESP_TYPE_B_FORMAT_1
-		} else {
-			return ResponsePacket{}, errors.New("Invalid Outcome Packet!")
-		}
+// Waveform 값에서 0으로 취급하는 값 (12bit의 중간값)
+const WaveformOffset = 2048
 
-		switch flag {
-		case RESP_TYPE_A:
-			return ResponsePacket{
-				ResponseType: RESP_TYPE_A,
-				Identifier:   raw[1],
-				Values:       raw[2:7],
-			}, nil
-		case RESP_TYPE_B_FORMAT_1:
-			return ResponsePacket{
-				ResponseType:     RESP_TYPE_B_FORMAT_1,
-				Identifier:       raw[1],
-				DeviceIdentifier: []byte{raw[2]},
-				Values:           raw[3:7],
-			}, nil
-		case RESP_TYPE_B_FORMAT_2:
-			return ResponsePacket{
-				ResponseType:     RESP_TYPE_B_FORMAT_2,
-				DeviceIdentifier: raw[1:3],
-				Values:           raw[3:7],
-			}, nil
-		}
-	}
+// High와 Low 바이트의 하위 6bit씩을 합쳐 12bit 값으로 디코딩합니다.
+// ConvertBitWaveform, BitArrayToInteger와 같은 결과를 메모리 할당 없이 계산합니다.
+func Decode12Bit(high byte, low byte) uint16 {
+	return uint16(high&0x3F)<<6 | uint16(low&0x3F)
+}
 
-	return ResponsePacket{}, errors.New("Invalid Outcome Packet!")
+// Decode12Bit의 결과에서 WaveformOffset을 뺀 부호있는 값
+func DecodeSigned12Bit(high byte, low byte) int {
+	return int(Decode12Bit(high, low)) - WaveformOffset
 }
 
 func ConvertBitWaveform(high byte, low byte) []uint8 {
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/sirupsen/logrus"
+	"go.bug.st/serial.v1"
+)
+
+var QueryOptions struct {
+	Debug    bool          `short:"d" long:"debug" description:"Enable Debug Mode." optional:"true"`
+	Port     string        `short:"p" long:"port" description:"Port which connected with Device" required:"true"`
+	Watch    bool          `short:"w" long:"watch" description:"Repeat the query every interval" optional:"true"`
+	Interval time.Duration `short:"i" long:"interval" description:"Interval of watch mode" default:"1s"`
+	Args     struct {
+		Identifier string `positional-arg-name:"IDENTIFIER" description:"Identifier number or name of TypeIntString"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// query 서브커맨드: 하나의 Identifier를 요청하고 결과를 출력합니다.
+// ex) signalize query -p /dev/ttyUSB0 "Tidal Volume" --watch -i 500ms
+func RunQuery(args []string) int {
+	if _, err := flags.ParseArgs(&QueryOptions, args); err != nil {
+		return 1
+	}
+
+	if QueryOptions.Debug {
+		log.Level = logrus.DebugLevel
+	} else {
+		log.Level = logrus.ErrorLevel
+	}
+
+	identifier, err := packet.LookupIdentifier(QueryOptions.Args.Identifier)
+	if err != nil {
+		log.Errorln("Identifier를 찾지 못했습니다.")
+		log.Errorln(err)
+		return 1
+	}
+
+	ser := OpenPort(QueryOptions.Port, SerialMode())
+	defer ser.Close()
+
+	for {
+		if err := Query(ser, packet.RequestPacket{Identifier: identifier}); err != nil {
+			log.Errorln(err)
+			if !QueryOptions.Watch {
+				return 1
+			}
+		}
+
+		if !QueryOptions.Watch {
+			return 0
+		}
+
+		time.Sleep(QueryOptions.Interval)
+	}
+}
+
+func Query(ser serial.Port, request packet.RequestPacket) error {
+	var raw = request.ToBytes()
+	fmt.Printf("[%s] >> % X\n", time.Now().Format("15:04:05.000"), raw)
+
+	if _, err := ser.Write(raw); err != nil {
+		return err
+	}
+
+	result, err := ReadFromSerial(ser)
+	fmt.Printf("[%s] << % X\n", time.Now().Format("15:04:05.000"), result)
+	if err != nil {
+		return err
+	}
+
+	response, err := packet.ParseResponseFor(request, result)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%+v\n", response.Packet())
+	PrintDecoded(int(request.Identifier), response)
+	return nil
+}
+
+func PrintDecoded(identifier int, response packet.Response) {
+	var waveforms = func(samples []packet.ChannelSample) {
+		for _, sample := range samples {
+			fmt.Printf("  %-12s %8.1f %-6s (%d)\n", sample.Key, sample.Value(), sample.Unit, sample.Count)
+		}
+	}
+
+	switch response := response.(type) {
+	case packet.ErrorResponse:
+		fmt.Println("  RERROR: 장비가 요청을 처리하지 못했습니다.")
+	case packet.NumericResponse:
+		value, err := response.Value()
+		if err != nil {
+			fmt.Printf("  %s (%d): %q\n", response.Name(), identifier, response.Values)
+			return
+		}
+
+		fmt.Printf("  %s (%d): %v %s\n", response.Name(), identifier, value, response.Unit())
+	case packet.IdentityResponse:
+		fmt.Printf("  %s (%d): %q %q\n", packet.TypeIntString[identifier], identifier, response.DeviceIdentifier, response.Text())
+	case packet.OnlineValues34:
+		fmt.Printf("  Ventilator Status: %08b\n", response.VentilatorStatus)
+		waveforms(response.Samples())
+	case packet.OnlineValues120:
+		fmt.Printf("  Ventilator Status: %08b\n", response.VentilatorStatus)
+		waveforms(response.Samples())
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/storage"
+
+	"github.com/jessevdk/go-flags"
+)
+
+var RecordsOptions struct {
+	Directory string   `short:"D" long:"directory" description:"Directory of local storage" required:"true"`
+	UDID      string   `short:"u" long:"udid" description:"Device to export, all devices when not given"`
+	From      string   `short:"f" long:"from" description:"Start of the range in RFC3339"`
+	To        string   `short:"t" long:"to" description:"End of the range in RFC3339"`
+	Types     []string `long:"type" description:"Types to export (repeatable)"`
+	Keys      []string `long:"key" description:"Keys to export (repeatable)"`
+	Format    string   `long:"format" description:"Output format" default:"json" choice:"json" choice:"csv"`
+	Output    string   `short:"o" long:"output" description:"Output file, stdout when not given"`
+	Devices   bool     `long:"devices" description:"Print stored devices and exit"`
+}
+
+// records 서브커맨드: 로컬 저장소의 데이터를 시간 범위로 내보냅니다.
+// ex) signalize records -D /var/lib/signalize --from 2026-10-19T09:00:00+09:00 --type Numeric --format csv -o numerics.csv
+func RunRecords(args []string) int {
+	if _, err := flags.ParseArgs(&RecordsOptions, args); err != nil {
+		return 1
+	}
+
+	var query = storage.Query{
+		UDID:  RecordsOptions.UDID,
+		Types: RecordsOptions.Types,
+		Keys:  RecordsOptions.Keys,
+	}
+
+	for _, bound := range []struct {
+		text string
+		time *time.Time
+	}{{RecordsOptions.From, &query.From}, {RecordsOptions.To, &query.To}} {
+		if bound.text == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, bound.text)
+		if err != nil {
+			log.Errorln("시간은 RFC3339 형식이어야 합니다. (ex. 2026-10-19T09:00:00+09:00)")
+			log.Errorln(err)
+			return 1
+		}
+
+		*bound.time = parsed
+	}
+
+	store, err := storage.OpenReadOnly(RecordsOptions.Directory)
+	if err != nil {
+		log.Errorln(err)
+		return 1
+	}
+
+	if RecordsOptions.Devices {
+		devices, err := store.Devices()
+		if err != nil {
+			log.Errorln(err)
+			return 1
+		}
+
+		for _, device := range devices {
+			os.Stdout.WriteString(device + "\n")
+		}
+
+		return 0
+	}
+
+	var output = os.Stdout
+	if RecordsOptions.Output != "" {
+		if output, err = os.Create(RecordsOptions.Output); err != nil {
+			log.Errorln(err)
+			return 1
+		}
+
+		defer output.Close()
+	}
+
+	if err := store.Export(output, RecordsOptions.Format, query); err != nil {
+		log.Errorln("데이터를 내보내지 못했습니다.")
+		log.Errorln(err)
+		return 1
+	}
+
+	return 0
+}
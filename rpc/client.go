@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Signalize 서비스의 Go 클라이언트
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// address는 host:port 또는 unix:///path/to/socket 입니다. TLS 없이 연결합니다.
+func Dial(address string, options ...grpc.DialOption) (*Client, error) {
+	options = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(Codec{})),
+	}, options...)
+
+	conn, err := grpc.NewClient(address, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{conn: conn}, nil
+}
+
+func (client *Client) ListDevices(ctx context.Context) ([]Device, error) {
+	var response = &ListDevicesResponse{}
+	if err := client.conn.Invoke(ctx, "/"+ServiceName+"/ListDevices", &ListDevicesRequest{}, response); err != nil {
+		return nil, err
+	}
+
+	return response.Devices, nil
+}
+
+func (client *Client) GetDevice(ctx context.Context, udid string) (*Device, error) {
+	var device = &Device{}
+	if err := client.conn.Invoke(ctx, "/"+ServiceName+"/GetDevice", &DeviceRequest{UDID: udid}, device); err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+func (client *Client) LatestNumerics(ctx context.Context, udid string, keys ...string) ([]mq.Record, error) {
+	var response = &NumericsResponse{}
+	if err := client.conn.Invoke(ctx, "/"+ServiceName+"/LatestNumerics", &NumericsRequest{UDID: udid, Keys: keys}, response); err != nil {
+		return nil, err
+	}
+
+	return response.Records, nil
+}
+
+// 스트림으로 받는 메시지들, ctx를 취소하면 끝납니다.
+type RecordStream struct {
+	stream grpc.ClientStream
+}
+
+func (stream *RecordStream) Recv() (mq.Record, error) {
+	var record mq.Record
+	err := stream.stream.RecvMsg(&record)
+	return record, err
+}
+
+func (client *Client) StreamWaveforms(ctx context.Context, request StreamRequest) (*RecordStream, error) {
+	return client.open(ctx, &ServiceDesc.Streams[0], request)
+}
+
+func (client *Client) StreamEvents(ctx context.Context, request StreamRequest) (*RecordStream, error) {
+	return client.open(ctx, &ServiceDesc.Streams[1], request)
+}
+
+func (client *Client) open(ctx context.Context, desc *grpc.StreamDesc, request StreamRequest) (*RecordStream, error) {
+	stream, err := client.conn.NewStream(ctx, desc, "/"+ServiceName+"/"+desc.StreamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := stream.SendMsg(&request); err != nil {
+		return nil, err
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	return &RecordStream{stream: stream}, nil
+}
+
+func (client *Client) Close() error {
+	return client.conn.Close()
+}
@@ -0,0 +1,375 @@
+package rpc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// schema/signalize.proto의 메시지들, protoc 없이 mq.MarshalProtobuf와 같은 방식으로 직접 인코딩합니다.
+// 필드를 바꾸면 schema/signalize.proto도 같이 고쳐야 합니다.
+
+type Device struct {
+	UDID      string
+	Number    string // Identifier 86으로 받은 Ventilator 번호
+	Host      string
+	Port      string
+	Session   string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Channels  []string // 받은 Waveform 채널들
+}
+
+type ListDevicesRequest struct{}
+
+type ListDevicesResponse struct {
+	Devices []Device
+}
+
+type DeviceRequest struct {
+	UDID string
+}
+
+type NumericsRequest struct {
+	UDID string
+	Keys []string
+}
+
+type NumericsResponse struct {
+	Records []mq.Record
+}
+
+// Types, Keys가 비어있으면 모두 받습니다. Block은 Waveform을 채널마다 묶을 샘플 수입니다.
+type StreamRequest struct {
+	UDID  string
+	Types []string
+	Keys  []string
+	Block int32
+}
+
+// 기본값("", 0)인 필드는 proto3처럼 쓰지 않습니다.
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendVarint(b []byte, number protowire.Number, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, value)
+}
+
+// google.protobuf.Timestamp
+func appendTimestamp(b []byte, number protowire.Number, t time.Time) []byte {
+	var message []byte
+	message = appendVarint(message, 1, uint64(t.Unix()))
+	message = appendVarint(message, 2, uint64(t.Nanosecond()))
+	return appendMessage(b, number, message)
+}
+
+func appendStrings(b []byte, number protowire.Number, values []string) []byte {
+	for _, value := range values {
+		b = protowire.AppendTag(b, number, protowire.BytesType)
+		b = protowire.AppendString(b, value)
+	}
+
+	return b
+}
+
+func appendMessage(b []byte, number protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// 메시지의 필드를 차례대로 읽어 field를 호출합니다.
+func consumeFields(raw []byte, field func(number protowire.Number, kind protowire.Type, value []byte) int) error {
+	for len(raw) > 0 {
+		number, kind, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		raw = raw[n:]
+		if n = field(number, kind, raw); n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		raw = raw[n:]
+	}
+
+	return nil
+}
+
+func consumeTimestamp(raw []byte) (t time.Time, err error) {
+	var seconds, nanos uint64
+	err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		switch {
+		case number == 1 && kind == protowire.VarintType:
+			var n int
+			seconds, n = protowire.ConsumeVarint(value)
+			return n
+		case number == 2 && kind == protowire.VarintType:
+			var n int
+			nanos, n = protowire.ConsumeVarint(value)
+			return n
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+
+	return time.Unix(int64(seconds), int64(nanos)).UTC(), err
+}
+
+// 문자열 필드 하나를 읽어 target에 넣습니다. (repeated면 append)
+func consumeString(kind protowire.Type, value []byte, set func(string)) int {
+	if kind != protowire.BytesType {
+		return -1
+	}
+
+	text, n := protowire.ConsumeString(value)
+	if n >= 0 {
+		set(text)
+	}
+
+	return n
+}
+
+func (device *Device) marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, device.UDID)
+	b = appendString(b, 2, device.Number)
+	b = appendString(b, 3, device.Host)
+	b = appendString(b, 4, device.Port)
+	b = appendString(b, 5, device.Session)
+	if !device.FirstSeen.IsZero() {
+		b = appendTimestamp(b, 6, device.FirstSeen)
+	}
+
+	if !device.LastSeen.IsZero() {
+		b = appendTimestamp(b, 7, device.LastSeen)
+	}
+
+	return appendStrings(b, 8, device.Channels)
+}
+
+func (device *Device) unmarshal(raw []byte) error {
+	var nested error
+	var err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		switch number {
+		case 1:
+			return consumeString(kind, value, func(text string) { device.UDID = text })
+		case 2:
+			return consumeString(kind, value, func(text string) { device.Number = text })
+		case 3:
+			return consumeString(kind, value, func(text string) { device.Host = text })
+		case 4:
+			return consumeString(kind, value, func(text string) { device.Port = text })
+		case 5:
+			return consumeString(kind, value, func(text string) { device.Session = text })
+		case 6, 7:
+			message, n := protowire.ConsumeBytes(value)
+			if n >= 0 && kind == protowire.BytesType {
+				var t time.Time
+				if t, nested = consumeTimestamp(message); number == 6 {
+					device.FirstSeen = t
+				} else {
+					device.LastSeen = t
+				}
+			}
+
+			return n
+		case 8:
+			return consumeString(kind, value, func(text string) { device.Channels = append(device.Channels, text) })
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+
+	if err == nil {
+		err = nested
+	}
+
+	return err
+}
+
+func (request *ListDevicesRequest) marshal() []byte { return nil }
+
+func (request *ListDevicesRequest) unmarshal(raw []byte) error {
+	return consumeFields(raw, protowire.ConsumeFieldValue)
+}
+
+func (response *ListDevicesResponse) marshal() []byte {
+	var b []byte
+	for _, device := range response.Devices {
+		b = appendMessage(b, 1, device.marshal())
+	}
+
+	return b
+}
+
+func (response *ListDevicesResponse) unmarshal(raw []byte) error {
+	var nested error
+	var err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		if number != 1 || kind != protowire.BytesType {
+			return protowire.ConsumeFieldValue(number, kind, value)
+		}
+
+		message, n := protowire.ConsumeBytes(value)
+		if n >= 0 {
+			var device Device
+			if nested = device.unmarshal(message); nested == nil {
+				response.Devices = append(response.Devices, device)
+			}
+		}
+
+		return n
+	})
+
+	if err == nil {
+		err = nested
+	}
+
+	return err
+}
+
+func (request *DeviceRequest) marshal() []byte {
+	return appendString(nil, 1, request.UDID)
+}
+
+func (request *DeviceRequest) unmarshal(raw []byte) error {
+	return consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		if number == 1 {
+			return consumeString(kind, value, func(text string) { request.UDID = text })
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+}
+
+func (request *NumericsRequest) marshal() []byte {
+	return appendStrings(appendString(nil, 1, request.UDID), 2, request.Keys)
+}
+
+func (request *NumericsRequest) unmarshal(raw []byte) error {
+	return consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		switch number {
+		case 1:
+			return consumeString(kind, value, func(text string) { request.UDID = text })
+		case 2:
+			return consumeString(kind, value, func(text string) { request.Keys = append(request.Keys, text) })
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+}
+
+func (response *NumericsResponse) marshal() []byte {
+	var b []byte
+	for _, record := range response.Records {
+		b = appendMessage(b, 1, mq.MarshalProtobuf(record))
+	}
+
+	return b
+}
+
+func (response *NumericsResponse) unmarshal(raw []byte) error {
+	var nested error
+	var err = consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		if number != 1 || kind != protowire.BytesType {
+			return protowire.ConsumeFieldValue(number, kind, value)
+		}
+
+		message, n := protowire.ConsumeBytes(value)
+		if n >= 0 {
+			var record mq.Record
+			if record, nested = mq.UnmarshalProtobuf(message); nested == nil {
+				response.Records = append(response.Records, record)
+			}
+		}
+
+		return n
+	})
+
+	if err == nil {
+		err = nested
+	}
+
+	return err
+}
+
+func (request *StreamRequest) marshal() []byte {
+	var b = appendString(nil, 1, request.UDID)
+	b = appendStrings(b, 2, request.Types)
+	b = appendStrings(b, 3, request.Keys)
+	return appendVarint(b, 4, uint64(request.Block))
+}
+
+func (request *StreamRequest) unmarshal(raw []byte) error {
+	return consumeFields(raw, func(number protowire.Number, kind protowire.Type, value []byte) int {
+		switch number {
+		case 1:
+			return consumeString(kind, value, func(text string) { request.UDID = text })
+		case 2:
+			return consumeString(kind, value, func(text string) { request.Types = append(request.Types, text) })
+		case 3:
+			return consumeString(kind, value, func(text string) { request.Keys = append(request.Keys, text) })
+		case 4:
+			if kind != protowire.VarintType {
+				return -1
+			}
+
+			block, n := protowire.ConsumeVarint(value)
+			request.Block = int32(block)
+			return n
+		}
+
+		return protowire.ConsumeFieldValue(number, kind, value)
+	})
+}
+
+type message interface {
+	marshal() []byte
+	unmarshal(raw []byte) error
+}
+
+// 위의 메시지들과 mq.Record를 Protobuf로 인코딩하는 gRPC 코덱
+// 이름이 "proto"이므로 schema/signalize.proto로 만든 다른 언어의 클라이언트와 그대로 통신할 수 있습니다.
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "proto"
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case message:
+		return v.marshal(), nil
+	case *mq.Record:
+		return mq.MarshalProtobuf(*v), nil
+	}
+
+	return nil, fmt.Errorf("Unknown Message Type %T", v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case message:
+		return v.unmarshal(data)
+	case *mq.Record:
+		record, err := mq.UnmarshalProtobuf(data)
+		*v = record
+		return err
+	}
+
+	return fmt.Errorf("Unknown Message Type %T", v)
+}
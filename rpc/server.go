@@ -0,0 +1,419 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/live"
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const ServiceName = "biosignal.v2.Signalize"
+
+type subscriber struct {
+	filter  live.Filter
+	send    chan mq.Record
+	blocks  map[string]*mq.Record
+	dropped uint64
+}
+
+// Waveform 샘플을 기기, 채널마다 묶고, Block개가 차면 묶인 블록을 반환합니다.
+func (stream *subscriber) block(record mq.Record) (block mq.Record, full bool) {
+	var id = record.UDID + "\x00" + record.Key
+	pending, ok := stream.blocks[id]
+	if !ok {
+		var first = record
+		pending = &first
+		stream.blocks[id] = pending
+	} else {
+		pending.WaveformValue = append(append([]float64{}, pending.WaveformValue...), record.WaveformValue...)
+		pending.WaveformRaw = append(append([]int{}, pending.WaveformRaw...), record.WaveformRaw...)
+		pending.Quality = record.Quality
+	}
+
+	if len(pending.WaveformValue) < stream.filter.Block {
+		return block, false
+	}
+
+	delete(stream.blocks, id)
+	return *pending, true
+}
+
+// 다른 출력들과 같은 데이터를 받아 gRPC로 제공하는 출력
+// 기기 목록과 기기마다 마지막 Numeric 값을 기억하고, 스트림마다 Buffer개까지 쌓아두고 가득 차면 새 메시지를 버립니다.
+type Server struct {
+	Buffer int
+
+	lock     sync.Mutex
+	devices  map[string]*Device
+	numerics map[string]map[string]mq.Record
+	streams  map[*subscriber]bool
+	closed   bool
+	dropped  uint64
+}
+
+func NewServer() *Server {
+	return &Server{
+		Buffer:   256,
+		devices:  map[string]*Device{},
+		numerics: map[string]map[string]mq.Record{},
+		streams:  map[*subscriber]bool{},
+	}
+}
+
+// 기기의 Ventilator 번호를 기억합니다.
+func (server *Server) SetNumber(udid string, number string) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.device(udid).Number = number
+}
+
+// 잠금을 잡은 상태에서 호출해야 합니다.
+func (server *Server) device(udid string) *Device {
+	device, ok := server.devices[udid]
+	if !ok {
+		device = &Device{UDID: udid}
+		server.devices[udid] = device
+	}
+
+	return device
+}
+
+func (server *Server) Send(d mq.QueueModel) error {
+	var record = d.Record()
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	var device = server.device(record.UDID)
+	if record.Host != "" {
+		device.Host = record.Host
+	}
+
+	if record.Port != "" {
+		device.Port = record.Port
+	}
+
+	if record.Session != "" {
+		device.Session = record.Session
+	}
+
+	if device.FirstSeen.IsZero() {
+		device.FirstSeen = record.Timestamp
+	}
+
+	device.LastSeen = record.Timestamp
+
+	switch record.Type {
+	case "Waveform":
+		if !contains(device.Channels, record.Key) {
+			device.Channels = append(device.Channels, record.Key)
+			sort.Strings(device.Channels)
+		}
+	case "Numeric", "Derived":
+		if server.numerics[record.UDID] == nil {
+			server.numerics[record.UDID] = map[string]mq.Record{}
+		}
+
+		server.numerics[record.UDID][record.Key] = record
+	}
+
+	for stream := range server.streams {
+		if !stream.filter.Match(d) {
+			continue
+		}
+
+		if record.Type == "Waveform" && stream.filter.Block > 1 {
+			block, full := stream.block(record)
+			if !full {
+				continue
+			}
+
+			server.enqueue(stream, block)
+			continue
+		}
+
+		server.enqueue(stream, record)
+	}
+
+	return nil
+}
+
+// 잠금을 잡은 상태에서 호출해야 합니다.
+func (server *Server) enqueue(stream *subscriber, record mq.Record) {
+	select {
+	case stream.send <- record:
+	default:
+		stream.dropped++
+		atomic.AddUint64(&server.dropped, 1)
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (server *Server) ListDevices(ctx context.Context, request *ListDevicesRequest) (*ListDevicesResponse, error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	var response = &ListDevicesResponse{Devices: []Device{}}
+	for _, device := range server.devices {
+		response.Devices = append(response.Devices, copyDevice(device))
+	}
+
+	sort.Slice(response.Devices, func(i, j int) bool {
+		return response.Devices[i].UDID < response.Devices[j].UDID
+	})
+
+	return response, nil
+}
+
+func copyDevice(device *Device) Device {
+	var result = *device
+	result.Channels = append([]string{}, device.Channels...)
+	return result
+}
+
+func (server *Server) GetDevice(ctx context.Context, request *DeviceRequest) (*Device, error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	device, ok := server.devices[request.UDID]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "Unknown Device %q", request.UDID)
+	}
+
+	var result = copyDevice(device)
+	return &result, nil
+}
+
+// 기기의 마지막 Numeric, Derived 값들, Keys가 있으면 그 값들만 돌려줍니다.
+func (server *Server) LatestNumerics(ctx context.Context, request *NumericsRequest) (*NumericsResponse, error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if _, ok := server.devices[request.UDID]; !ok {
+		return nil, status.Errorf(codes.NotFound, "Unknown Device %q", request.UDID)
+	}
+
+	var response = &NumericsResponse{Records: []mq.Record{}}
+	for key, record := range server.numerics[request.UDID] {
+		if len(request.Keys) == 0 || containsFold(request.Keys, key) {
+			response.Records = append(response.Records, record)
+		}
+	}
+
+	sort.Slice(response.Records, func(i, j int) bool {
+		return response.Records[i].Key < response.Records[j].Key
+	})
+
+	return response, nil
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Waveform을 채널마다 Block개의 샘플로 묶어서 보냅니다.
+func (server *Server) StreamWaveforms(request *StreamRequest, stream grpc.ServerStream) error {
+	return server.stream(live.Filter{UDID: request.UDID, Types: []string{"Waveform"}, Keys: request.Keys, Block: int(request.Block)}, stream)
+}
+
+// Event, Diagnostic, Alarm (또는 Types에 준 타입의) 메시지를 보냅니다.
+func (server *Server) StreamEvents(request *StreamRequest, stream grpc.ServerStream) error {
+	var types = request.Types
+	if len(types) == 0 {
+		types = []string{"Event", "Diagnostic", "Alarm"}
+	}
+
+	return server.stream(live.Filter{UDID: request.UDID, Types: types, Keys: request.Keys, Block: 1}, stream)
+}
+
+// 클라이언트가 끊거나 서버가 닫힐 때까지 filter에 맞는 데이터를 보냅니다.
+func (server *Server) stream(filter live.Filter, stream grpc.ServerStream) error {
+	if filter.Block < 1 {
+		filter.Block = 1
+	}
+
+	var subscriber = &subscriber{filter: filter, send: make(chan mq.Record, server.Buffer), blocks: map[string]*mq.Record{}}
+
+	server.lock.Lock()
+	if server.closed {
+		server.lock.Unlock()
+		return status.Error(codes.Unavailable, "Server Closed")
+	}
+
+	server.streams[subscriber] = true
+	server.lock.Unlock()
+
+	defer server.remove(subscriber)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case record, ok := <-subscriber.send:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(&record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (server *Server) remove(subscriber *subscriber) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	if server.streams[subscriber] {
+		delete(server.streams, subscriber)
+		close(subscriber.send)
+	}
+}
+
+// 열려있는 스트림 수
+func (server *Server) Streams() int {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	return len(server.streams)
+}
+
+// 느린 스트림 때문에 버린 메시지 수
+func (server *Server) Dropped() uint64 {
+	return atomic.LoadUint64(&server.dropped)
+}
+
+// 모든 스트림을 끝냅니다.
+func (server *Server) Close() error {
+	server.lock.Lock()
+	server.closed = true
+	var streams = []*subscriber{}
+	for stream := range server.streams {
+		streams = append(streams, stream)
+	}
+	server.lock.Unlock()
+
+	for _, stream := range streams {
+		server.remove(stream)
+	}
+
+	return nil
+}
+
+// grpc.ServiceDesc가 확인하는 서버 인터페이스
+type service interface {
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	GetDevice(context.Context, *DeviceRequest) (*Device, error)
+	LatestNumerics(context.Context, *NumericsRequest) (*NumericsResponse, error)
+	StreamWaveforms(*StreamRequest, grpc.ServerStream) error
+	StreamEvents(*StreamRequest, grpc.ServerStream) error
+}
+
+func unary(name string, request func() interface{}, call func(service, context.Context, interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, decode func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			var message = request()
+			if err := decode(message); err != nil {
+				return nil, err
+			}
+
+			if interceptor == nil {
+				return call(srv.(service), ctx, message)
+			}
+
+			var info = &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}
+			return interceptor(ctx, message, info, func(ctx context.Context, message interface{}) (interface{}, error) {
+				return call(srv.(service), ctx, message)
+			})
+		},
+	}
+}
+
+func streaming(name string, call func(service, *StreamRequest, grpc.ServerStream) error) grpc.StreamDesc {
+	return grpc.StreamDesc{
+		StreamName:    name,
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			var request = new(StreamRequest)
+			if err := stream.RecvMsg(request); err != nil {
+				return err
+			}
+
+			return call(srv.(service), request, stream)
+		},
+	}
+}
+
+// schema/signalize.proto의 service Signalize
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("ListDevices", func() interface{} { return &ListDevicesRequest{} }, func(srv service, ctx context.Context, request interface{}) (interface{}, error) {
+			return srv.ListDevices(ctx, request.(*ListDevicesRequest))
+		}),
+		unary("GetDevice", func() interface{} { return &DeviceRequest{} }, func(srv service, ctx context.Context, request interface{}) (interface{}, error) {
+			return srv.GetDevice(ctx, request.(*DeviceRequest))
+		}),
+		unary("LatestNumerics", func() interface{} { return &NumericsRequest{} }, func(srv service, ctx context.Context, request interface{}) (interface{}, error) {
+			return srv.LatestNumerics(ctx, request.(*NumericsRequest))
+		}),
+	},
+	Streams: []grpc.StreamDesc{
+		streaming("StreamWaveforms", service.StreamWaveforms),
+		streaming("StreamEvents", service.StreamEvents),
+	},
+	Metadata: "schema/signalize.proto",
+}
+
+// Codec을 쓰는 gRPC 서버를 만들고 server를 등록합니다.
+func (server *Server) Register(options ...grpc.ServerOption) *grpc.Server {
+	var grpcServer = grpc.NewServer(append([]grpc.ServerOption{grpc.ForceServerCodec(Codec{})}, options...)...)
+	grpcServer.RegisterService(&ServiceDesc, server)
+	return grpcServer
+}
+
+// address는 host:port 또는 unix:///path/to/socket 입니다.
+// Unix 소켓 파일이 이미 있으면 지우고 다시 만듭니다.
+func Listen(address string) (net.Listener, error) {
+	if !strings.HasPrefix(address, "unix://") {
+		return net.Listen("tcp", address)
+	}
+
+	var path = strings.TrimPrefix(address, "unix://")
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return net.Listen("unix", path)
+}
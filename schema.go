@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+
+	"github.com/jessevdk/go-flags"
+)
+
+var SchemaOptions struct {
+	Version int `short:"v" long:"version" description:"Schema version to print" default:"2" choice:"1" choice:"2"`
+}
+
+// schema 서브커맨드: 보내는 메시지의 JSON Schema를 출력합니다.
+// ex) signalize schema -v 2 > schema/record.v2.json
+func RunSchema(args []string) int {
+	if _, err := flags.ParseArgs(&SchemaOptions, args); err != nil {
+		return 1
+	}
+
+	schema, err := mq.JSONSchema(SchemaOptions.Version)
+	if err != nil {
+		log.Errorln(err)
+		return 1
+	}
+
+	fmt.Println(string(schema))
+	return 0
+}
@@ -6,38 +6,187 @@ import (
 	"errors"
 	"net"
 	"os"
-	"strconv"
 	"time"
 
+	"github.com/Hazealign/biosignal-hamilton-interface/analysis"
+	"github.com/Hazealign/biosignal-hamilton-interface/fhir"
+	"github.com/Hazealign/biosignal-hamilton-interface/hl7"
+	"github.com/Hazealign/biosignal-hamilton-interface/live"
 	"github.com/Hazealign/biosignal-hamilton-interface/mq"
 	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+	"github.com/Hazealign/biosignal-hamilton-interface/rpc"
+	"github.com/Hazealign/biosignal-hamilton-interface/storage"
 
+	"github.com/bitly/go-nsq"
 	"github.com/jessevdk/go-flags"
 	"github.com/sirupsen/logrus"
 	"go.bug.st/serial.v1"
 )
 
 var Options struct {
-	Debug      bool   `short:"d" long:"debug" description:"Enable Debug Mode." optional:"true"`
-	Port       string `short:"p" long:"port" description:"Port which connected with Device" required:"true"`
-	NsqAddress string `short:"a" long:"address" description:"Address of NSQ Server" required:"true"`
+	ConfigFile func(string) error `long:"config" description:"Read options from INI file, options after it override the file" no-ini:"true"`
+
+	Debug      bool     `short:"d" long:"debug" description:"Enable Debug Mode." optional:"true"`
+	Port       string   `short:"p" long:"port" description:"Port which connected with Device" required:"true"`
+	NsqAddress []string `short:"a" long:"address" description:"Address of nsqd, publishes to the first healthy one (repeatable)"`
+
+	AdminAddress string   `long:"admin-address" description:"host:port of admin HTTP server serving /metrics, /records and /live"`
+	AdminOrigins []string `long:"admin-allow-origin" description:"Origin of web pages allowed to connect /live WebSocket (repeatable, * for any)"`
+	LiveBuffer   int      `long:"live-buffer" description:"Number of messages queued for each /live client before dropping" default:"256"`
+
+	Raw          bool              `long:"raw" description:"Publish raw 12bit counts of waveforms with physical values" optional:"true"`
+	Calibration  map[string]string `long:"calibration" description:"Override waveform calibration as KEY:GAIN:OFFSET (ex. FLOW:0.06:2048)"`
+	Nomenclature map[string]string `long:"mdc" description:"Override IEEE 11073 nomenclature as KEY:CODE:REFID (ex. 68:151976:MDC_PRESS_AWAY_END_EXP_POS)"`
+
+	FlowPhase bool `long:"flow-phase" description:"Find breath phases only from the sign of flow, ignoring the ventilator status"`
+
+	Trend      []time.Duration `long:"trend" description:"Interval of numeric trend summaries (repeatable)" default:"1m" default:"15m" default:"1h"`
+	TrendTopic string          `long:"trend-topic" description:"NSQ topic of numeric trend summaries" default:"BiosignalTrend"`
+
+	Topic  string   `long:"topic" description:"NSQ topics when no route matches, separated by comma" default:"Biosignal"`
+	Routes []string `long:"route" description:"Route records to topics as MATCH=>TOPIC[,TOPIC] (ex. type=Waveform=>hamilton.{udid}.waveform, repeatable)"`
+
+	SchemaVersion int    `long:"schema-version" description:"Message schema version of JSON messages, 1 keeps the Scheduler compatible shape, 2 opts in to the versioned record" default:"1" choice:"1" choice:"2"`
+	Encoding      string `long:"encoding" description:"Encoding of NSQ messages, binary encodings always use schema version 2" default:"json" choice:"json" choice:"protobuf" choice:"msgpack"`
+
+	HL7 struct {
+		Address              string        `long:"address" description:"host:port of MLLP listener to send HL7 ORU^R01 messages"`
+		SendingFacility      string        `long:"sending-facility" description:"MSH-4 Sending Facility"`
+		ReceivingApplication string        `long:"receiving-application" description:"MSH-5 Receiving Application"`
+		ReceivingFacility    string        `long:"receiving-facility" description:"MSH-6 Receiving Facility"`
+		PatientID            string        `long:"patient-id" description:"PID-3 Patient ID"`
+		AssigningAuthority   string        `long:"assigning-authority" description:"Assigning Authority of PID-3"`
+		PatientName          string        `long:"patient-name" description:"PID-5 Patient Name (FAMILY^GIVEN)"`
+		Location             string        `long:"location" description:"PV1-3 Assigned Patient Location (POC^ROOM^BED)"`
+		Timeout              time.Duration `long:"timeout" description:"Timeout of connecting and waiting ACK" default:"5s"`
+		Retries              int           `long:"retries" description:"Number of retries when ACK is not AA" default:"3"`
+	} `group:"HL7 Options" namespace:"hl7"`
+
+	FHIR struct {
+		Endpoint   string        `long:"endpoint" description:"Base URL of FHIR R4 server to post transaction bundles"`
+		Directory  string        `long:"directory" description:"Directory to write bundles when endpoint is not given"`
+		BlockSize  int           `long:"block-size" description:"Number of waveform samples in one SampledData observation" default:"50"`
+		Interval   time.Duration `long:"interval" description:"Interval of sending bundles" default:"10s"`
+		MaxBacklog int           `long:"max-backlog" description:"Number of unsent observations kept for retry, oldest are dropped over this, 0 for no limit" default:"10000"`
+	} `group:"FHIR Options" namespace:"fhir"`
+
+	Storage struct {
+		Directory string        `long:"directory" description:"Directory to keep acquired data locally"`
+		Segment   time.Duration `long:"segment" description:"Length of one segment file" default:"1h"`
+		Retention time.Duration `long:"retention" description:"Delete segments older than this, 0 keeps everything" default:"168h"`
+		MaxBytes  int64         `long:"max-bytes" description:"Delete oldest segments of a device over this size, 0 for no limit"`
+		BlockSize int           `long:"block-size" description:"Number of waveform samples in one stored line" default:"50"`
+	} `group:"Storage Options" namespace:"storage"`
+
+	GRPC struct {
+		Address string `long:"address" description:"host:port or unix:///path/to/socket of gRPC API server"`
+		Buffer  int    `long:"buffer" description:"Number of messages queued for each gRPC stream before dropping" default:"256"`
+	} `group:"gRPC Options" namespace:"grpc"`
+
+	NSQ struct {
+		TLS                bool   `long:"tls" description:"Connect to NSQ with TLS"`
+		CAFile             string `long:"tls-ca" description:"CA bundle (PEM) to verify NSQ server, system CAs when not given"`
+		CertFile           string `long:"tls-cert" description:"Client certificate (PEM)"`
+		KeyFile            string `long:"tls-key" description:"Private key of client certificate (PEM)"`
+		ServerName         string `long:"tls-server-name" description:"Server name to verify, host of address when not given"`
+		InsecureSkipVerify bool   `long:"tls-insecure-skip-verify" description:"Do not verify NSQ server certificate (testing only)"`
+		AuthSecret         string `long:"auth-secret" description:"NSQ auth secret" env:"SIGNALIZE_NSQ_AUTH_SECRET"`
+		Deflate            bool   `long:"deflate" description:"Compress NSQ connection with deflate"`
+		DeflateLevel       int    `long:"deflate-level" description:"Deflate level (1 ~ 9)" default:"6"`
+		Snappy             bool   `long:"snappy" description:"Compress NSQ connection with snappy"`
+
+		Lookupd         []string      `long:"lookupd" description:"HTTP address of nsqlookupd to discover nsqd (repeatable)"`
+		LookupdInterval time.Duration `long:"lookupd-interval" description:"Interval of discovering nsqd and checking failed nsqd" default:"30s"`
+		RetryAfter      time.Duration `long:"retry-after" description:"Time before publishing to failed nsqd again" default:"30s"`
+		FanOut          bool          `long:"fan-out" description:"Publish every message to all healthy nsqd"`
+
+		BatchWindow time.Duration `long:"batch-window" description:"Collect messages for this window and send them with MPUB, 0 sends every message immediately" default:"50ms"`
+		BatchSize   int           `long:"batch-size" description:"Send a batch when it has this many messages" default:"100"`
+		BatchBytes  int           `long:"batch-bytes" description:"Send a batch when it has this many bytes" default:"1048576"`
+		Defer       time.Duration `long:"defer" description:"Deliver messages to consumers after this delay with DPUB, disables batching"`
+	} `group:"NSQ Options" namespace:"nsq"`
+
+	Sign struct {
+		Algorithm string `long:"algorithm" description:"Sign every NSQ message" choice:"hmac-sha256" choice:"ed25519"`
+		KeyID     string `long:"key-id" description:"Key ID included in signatures"`
+		KeyFile   string `long:"key-file" description:"HMAC secret file or Ed25519 private key (PKCS#8 PEM)"`
+	} `group:"Signing Options" namespace:"sign"`
 }
 
 var log = logrus.New()
 
+// Waveform에서 호흡 단위의 값을 계산
+var segmenter *analysis.Segmenter
+
+// Numeric과 호흡 단위의 값으로 이탈 지표를 계산
+var derived *analysis.DerivedEngine
+
+// Waveform과 호흡 단위의 값으로 환자-벤틸레이터 비동기를 찾음
+var asynchrony *analysis.AsynchronyDetector
+
+// Waveform 채널별 신호 품질
+var quality *analysis.SignalQuality
+
+// Numeric 값의 Trend 요약
+var trend *analysis.TrendAggregator
+
+// 데이터를 내보낼 출력들
+var sinks = []mq.Sink{}
+
+// NSQ로 보내는 데이터의 인코더
+var nsqEncoder mq.Encoder
+
+// NSQ 연결 설정 (TLS, 인증, 압축)
+var nsqConfig *nsq.Config
+
+// NSQ 메시지 서명 키 (없으면 서명하지 않음)
+var nsqSigner mq.Signer
+
+// 메시지를 보낼 nsqd들
+var nsqPool *mq.BrokerPool
+
+// 보내는 데이터에 Session과 기기별 Sequence를 붙임
+var sequencer = mq.NewSequencer()
+
 // 가져와야할 Numeric Values
 var list = []byte{
 	40, 41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51, 87, 104, 105, 106, 107, 108, 110, 111,
 	35, 36, 37, 38, 39, 60, 61, 62, 63, 64, 65, 66, 67, 69, 70, 71, 72, 73, 74, 75, 76, 77, 78, 79,
 	103, 113, 114, 115, 116, 117, 118, 120, 121, 122,
+	// 알람 상태
+	88, 89, 90, 91, 92, 93, 94, 95, 96, 97, 98, 99, 100, 101, 102,
 }
 
+// 마지막으로 보낸 벤틸레이터 상태 (흡기/호기 비트 제외, 아직 없으면 -1)
+var ventilatorStatus = -1
+
 func main() {
 	// 사용자가 입력한 포트 받아오기
 	log.Formatter = new(logrus.TextFormatter)
 	log.Out = os.Stdout
 
-	if _, err := flags.ParseArgs(&Options, os.Args); err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "query" {
+		os.Exit(RunQuery(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Exit(RunSchema(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "records" {
+		os.Exit(RunRecords(os.Args[2:]))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(RunExport(os.Args[2:]))
+	}
+
+	var parser = flags.NewParser(&Options, flags.Default)
+	Options.ConfigFile = func(path string) error {
+		return flags.NewIniParser(parser).ParseFile(path)
+	}
+
+	if _, err := parser.ParseArgs(os.Args); err != nil {
 		log.Errorln("포트 번호가 명시되지 않았습니다")
 		log.Errorln(err)
 		os.Exit(1)
@@ -49,19 +198,205 @@ func main() {
 		}
 	}
 
-	// Serial 연결 설정 (Spec 문서 2.1)
-	config := &serial.Mode{
-		BaudRate: 9600,
-		Parity:   serial.EvenParity,
-		StopBits: serial.TwoStopBits,
+	for key, value := range Options.Calibration {
+		if err := packet.SetCalibration(key, value); err != nil {
+			log.Errorln("보정값이 잘못되었습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		}
+	}
+
+	for key, value := range Options.Nomenclature {
+		if err := packet.SetNomenclature(key, value); err != nil {
+			log.Errorln("명명법 코드가 잘못되었습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		}
+	}
+
+	if encoder, err := mq.NewEncoder(Options.Encoding, Options.SchemaVersion); err != nil {
+		log.Errorln(err)
+		os.Exit(1)
+	} else {
+		nsqEncoder = encoder
+	}
+
+	if config, err := (mq.ProducerConfig{
+		TLS:                Options.NSQ.TLS,
+		CAFile:             Options.NSQ.CAFile,
+		CertFile:           Options.NSQ.CertFile,
+		KeyFile:            Options.NSQ.KeyFile,
+		ServerName:         Options.NSQ.ServerName,
+		InsecureSkipVerify: Options.NSQ.InsecureSkipVerify,
+		AuthSecret:         Options.NSQ.AuthSecret,
+		Deflate:            Options.NSQ.Deflate,
+		DeflateLevel:       Options.NSQ.DeflateLevel,
+		Snappy:             Options.NSQ.Snappy,
+	}).NSQConfig(); err != nil {
+		log.Errorln("NSQ 연결 설정이 잘못되었습니다.")
+		log.Errorln(err)
+		os.Exit(1)
+	} else {
+		nsqConfig = config
+	}
+
+	if len(Options.NsqAddress) == 0 && len(Options.NSQ.Lookupd) == 0 {
+		log.Errorln("NSQ 주소(-a) 또는 nsqlookupd 주소(--nsq.lookupd)가 필요합니다.")
+		os.Exit(1)
+	}
+
+	nsqPool = mq.NewBrokerPool(Options.NsqAddress, nsqConfig)
+	nsqPool.Lookupd = Options.NSQ.Lookupd
+	nsqPool.FanOut = Options.NSQ.FanOut
+	nsqPool.RetryAfter = Options.NSQ.RetryAfter
+	if len(Options.NSQ.Lookupd) > 0 {
+		if err := nsqPool.Discover(); err != nil {
+			log.Warnln("nsqlookupd에서 nsqd를 찾지 못했습니다.")
+			log.Warnln(err)
+		}
+	}
+
+	if Options.NSQ.LookupdInterval <= 0 {
+		log.Warnf("--nsq.lookupd-interval이 0 이하이므로 %v마다 확인합니다.", mq.DefaultWatchInterval)
+	}
+
+	nsqPool.Watch(Options.NSQ.LookupdInterval, func(err error) {
+		log.Warnln("nsqlookupd에서 nsqd를 찾지 못했습니다.")
+		log.Warnln(err)
+	})
+
+	RegisterBrokerMetrics(nsqPool)
+	if Options.AdminAddress != "" {
+		ServeAdmin(Options.AdminAddress)
+	}
+
+	if Options.Sign.Algorithm != "" {
+		if signer, err := mq.LoadSigner(Options.Sign.Algorithm, Options.Sign.KeyID, Options.Sign.KeyFile); err != nil {
+			log.Errorln("서명 키를 읽지 못했습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		} else {
+			nsqSigner = signer
+		}
+	}
+
+	var router = &mq.Router{}
+	for _, rule := range append([]string{"*=>" + Options.Topic}, Options.Routes...) {
+		route, err := mq.ParseRoute(rule)
+		if err != nil {
+			log.Errorln("토픽 라우팅 규칙이 잘못되었습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		}
+
+		if router.Default == nil {
+			router.Default = route.Topics
+		} else {
+			router.Routes = append(router.Routes, route)
+		}
+	}
+
+	var nsqSink = mq.NSQSink{Pool: nsqPool, Router: router, Encoder: nsqEncoder, Signer: nsqSigner, Defer: Options.NSQ.Defer}
+	if Options.NSQ.BatchWindow > 0 && Options.NSQ.Defer == 0 {
+		var sink = mq.NewBatchSink(nsqSink, Options.NSQ.BatchSize, Options.NSQ.BatchBytes, Options.NSQ.BatchWindow)
+		sink.OnBatch = BatchMetrics()
+		// 배치를 보내는 고루틴에서 호출되므로 panic 대신 종료합니다.
+		sink.OnError = func(err error) {
+			log.Errorln("NSQ에 보내는 중 오류가 발생하였습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		}
+
+		sinks = append(sinks, sink)
+	} else {
+		sinks = append(sinks, nsqSink)
+	}
+
+	if Options.HL7.Address != "" {
+		var sink = hl7.NewMLLPSink(Options.HL7.Address, hl7.Header{
+			SendingApplication:   "BIOSIGNAL-HAMILTON",
+			SendingFacility:      Options.HL7.SendingFacility,
+			ReceivingApplication: Options.HL7.ReceivingApplication,
+			ReceivingFacility:    Options.HL7.ReceivingFacility,
+		}, hl7.Patient{
+			ID:                 Options.HL7.PatientID,
+			AssigningAuthority: Options.HL7.AssigningAuthority,
+			Name:               Options.HL7.PatientName,
+			Location:           Options.HL7.Location,
+		})
+
+		sink.Timeout = Options.HL7.Timeout
+		sink.Retries = Options.HL7.Retries
+		sink.OnError = func(err error) {
+			log.Errorln("HL7 메시지를 보내지 못했습니다.")
+			log.Errorln(err)
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	if Options.Storage.Directory != "" {
+		store, err := storage.Open(Options.Storage.Directory)
+		if err != nil {
+			log.Errorln("로컬 저장소를 열지 못했습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		}
+
+		store.Segment = Options.Storage.Segment
+		store.Retention = Options.Storage.Retention
+		store.MaxBytes = Options.Storage.MaxBytes
+		store.BlockSize = Options.Storage.BlockSize
+		if err := store.Prune(time.Now()); err != nil {
+			log.Warnln("오래된 세그먼트를 지우지 못했습니다.")
+			log.Warnln(err)
+		}
+
+		admin.Handle("/records", store)
+
+		sinks = append(sinks, store)
+	}
+
+	if Options.AdminAddress != "" {
+		var hub = live.NewHub()
+		hub.Buffer = Options.LiveBuffer
+		hub.AllowOrigins(Options.AdminOrigins)
+		RegisterLiveMetrics(hub)
+		admin.Handle("/live", hub)
+
+		sinks = append(sinks, hub)
+	}
+
+	var api *rpc.Server
+	if Options.GRPC.Address != "" {
+		api = rpc.NewServer()
+		api.Buffer = Options.GRPC.Buffer
+		RegisterRPCMetrics(api)
+
+		listener, err := rpc.Listen(Options.GRPC.Address)
+		if err != nil {
+			log.Errorln("gRPC 서버를 열지 못했습니다.")
+			log.Errorln(err)
+			os.Exit(1)
+		}
+
+		go func() {
+			if err := api.Register().Serve(listener); err != nil {
+				log.Errorln("gRPC 서버가 멈췄습니다.")
+				log.Errorln(err)
+			}
+		}()
+
+		sinks = append(sinks, api)
 	}
 
 	// Serial 포트 연결
-	ser := OpenPort(Options.Port, config)
-	ser.Write(packet.RequestPacket{
+	ser := OpenPort(Options.Port, SerialMode())
+	var correlator = packet.Correlator{}
+	ser.Write(correlator.Send(packet.RequestPacket{
 		Identifier: 0x56, // 0x56==86
 		// Identifier 86은 Ventilator 번호를 받아올 수 있음
-	}.ToBytes())
+	}))
 
 	res, err := ReadFromSerial(ser)
 	if err != nil {
@@ -70,7 +405,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	pkt, err := packet.ParseResponsePacket(res)
+	response, err := correlator.Receive(res)
 	if err != nil {
 		log.Errorln(res)
 		log.Errorln("에러가 발생했습니다.")
@@ -78,6 +413,7 @@ func main() {
 		os.Exit(1)
 	}
 
+	var pkt = response.Packet()
 	log.Debug("Data Input")
 	log.Debug(res)
 	log.Debug(pkt)
@@ -86,20 +422,76 @@ func main() {
 	result := hex.EncodeToString(crypt.Sum(nil))
 	var udid = string(result)
 	var host = GetHostAddress() + ":" + Options.Port
+
+	var number = ""
+	if identity, ok := response.(packet.IdentityResponse); ok {
+		number = identity.Text()
+	}
+
+	if api != nil {
+		api.SetNumber(udid, number)
+	}
+
+	if Options.FHIR.Endpoint != "" || Options.FHIR.Directory != "" {
+		var sink = fhir.NewBundleSink(Options.FHIR.Endpoint, Options.FHIR.Directory, fhir.NewDevice(udid, number))
+		sink.BlockSize = Options.FHIR.BlockSize
+		sink.Interval = Options.FHIR.Interval
+		sink.MaxBacklog = Options.FHIR.MaxBacklog
+		sink.OnError = func(err error) {
+			log.Errorln("FHIR Bundle을 보내지 못했습니다.")
+			log.Errorln(err)
+		}
+
+		sinks = append(sinks, sink)
+	}
 	var index = 0
 
+	derived = analysis.NewDerivedEngine(func(indicator analysis.Indicator) {
+		PublishIndicator(indicator, udid, host)
+	})
+
+	trend = analysis.NewTrendAggregator(Options.Trend, func(summary analysis.TrendSummary) {
+		PublishTrend(summary, udid, host)
+	})
+
+	quality = analysis.NewSignalQuality(func(event analysis.QualityEvent) {
+		PublishQuality(event, udid, host)
+	})
+
+	asynchrony = analysis.NewAsynchronyDetector(func(event analysis.AsynchronyEvent) {
+		PublishAsynchrony(event, udid, host)
+	}, func(index analysis.AsynchronyIndex) {
+		PublishIndicator(analysis.Indicator{
+			Timestamp: index.End,
+			Key:       "ASYNCHRONY_INDEX",
+			Value:     index.Index,
+			Unit:      "%",
+			Sources:   index.Sources(),
+		}, udid, host)
+	})
+
+	segmenter = analysis.NewSegmenter(func(breath analysis.Breath) {
+		PublishBreath(breath, udid, host)
+		derived.AddBreath(breath)
+		asynchrony.AddBreath(breath)
+	})
+
+	if !Options.FlowPhase {
+		segmenter.PhaseFromStatus = analysis.PhaseFromVentilatorStatus
+	}
+
 	for {
-		ser.Write(packet.RequestPacket{
+		ser.Write(correlator.Send(packet.RequestPacket{
 			Identifier: 120,
-		}.ToBytes())
+		}))
 
-		ReceiveWaveforms(ser, udid, host)
+		ReceiveWaveforms(&correlator, ser, udid, host)
 
-		ser.Write(packet.RequestPacket{
+		ser.Write(correlator.Send(packet.RequestPacket{
 			Identifier: list[index%len(list)],
-		}.ToBytes())
+		}))
 
-		ReceiveNumerics(int(list[index%len(list)]), ser, udid, host)
+		ReceiveNumerics(int(list[index%len(list)]), &correlator, ser, udid, host)
 		if index == len(list)*20 {
 			index = 1
 		} else {
@@ -108,129 +500,278 @@ func main() {
 	}
 }
 
-func ReceiveWaveforms(ser serial.Port, udid string, host string) {
-	result, err := ReadFromSerial(ser)
-	if err != nil {
+// 마지막으로 보낸 요청에 대한 응답을 읽어옵니다.
+// 늦게 도착한 이전 응답은 버리고 다시 읽으며, 그래도 맞지 않으면 입력 버퍼를 비우고 false를 반환합니다.
+func ReceiveResponse(correlator *packet.Correlator, ser serial.Port) (response packet.Response, ok bool) {
+	var result []byte
+	response, err := correlator.ReceiveFrom(func() ([]byte, error) {
+		var err error
+		result, err = ReadFromSerial(ser)
+		return result, err
+	})
+
+	if errors.Is(err, packet.ErrDeviceRERROR) {
+		log.Warnf("장비가 요청 %d을 처리하지 못했습니다. (RERROR)", correlator.Current().Identifier)
+		return response, false
+	}
+
+	var protocolError *packet.ProtocolError
+	if err != nil && !errors.As(err, &protocolError) {
 		log.Errorln("에러가 발생했습니다.")
 		log.Errorln(err)
 		os.Exit(1)
 	}
 
-	pkt, err := packet.ParseResponsePacket(result)
 	if err != nil {
-		log.Errorln(result)
-		log.Errorln(pkt)
-		log.Errorln("에러가 발생했습니다.")
-		log.Errorln(err)
-		os.Exit(1)
+		log.Warnln("요청과 맞지 않는 응답을 버립니다.")
+		log.Warnln(err)
+		if err := ser.ResetInputBuffer(); err != nil {
+			log.Warnln(err)
+		}
+
+		return nil, false
 	}
 
 	log.Debug("기기에서 전송된 데이터: ")
-	log.Debug(pkt)
+	log.Debug(response)
 	log.Debug(result)
 
-	err1 := mq.SendToNSQ(mq.QueueModel{
-		TIMESTAMP:  time.Now(),
-		KEY:        "P_PATIENT",
-		TYPE:       "Waveform",
-		HOST:       host,
-		VALUE_UNIT: "",
-		UDID:       udid,
-		WAVEFORM_VALUE: []int{
-			packet.BitArrayToInteger(packet.ConvertBitWaveform(pkt.PPatientHigh, pkt.PPatientLow)) - 2048,
-		},
-	}, Options.NsqAddress)
-
-	err2 := mq.SendToNSQ(mq.QueueModel{
-		TIMESTAMP:  time.Now(),
-		KEY:        "P_OPTIONAL",
-		TYPE:       "Waveform",
-		HOST:       host,
-		VALUE_UNIT: "",
-		UDID:       udid,
-		WAVEFORM_VALUE: []int{
-			packet.BitArrayToInteger(packet.ConvertBitWaveform(pkt.POptionalHigh, pkt.POptionalLow)) - 2048,
-		},
-	}, Options.NsqAddress)
-
-	err3 := mq.SendToNSQ(mq.QueueModel{
-		TIMESTAMP:  time.Now(),
-		KEY:        "FLOW",
-		TYPE:       "Waveform",
-		HOST:       host,
-		VALUE_UNIT: "",
-		UDID:       udid,
-		WAVEFORM_VALUE: []int{
-			packet.BitArrayToInteger(packet.ConvertBitWaveform(pkt.FlowHigh, pkt.FlowLow)) - 2048,
-		},
-	}, Options.NsqAddress)
-
-	err4 := mq.SendToNSQ(mq.QueueModel{
-		TIMESTAMP:  time.Now(),
-		KEY:        "VOLUME",
-		TYPE:       "Waveform",
-		HOST:       host,
-		VALUE_UNIT: "",
-		UDID:       udid,
-		WAVEFORM_VALUE: []int{
-			packet.BitArrayToInteger(packet.ConvertBitWaveform(pkt.VolumeHigh, pkt.VolumeLow)) - 2048,
-		},
-	}, Options.NsqAddress)
+	return response, true
+}
 
-	var errs = []error{err1, err2, err3, err4}
-	for _, err := range errs {
-		if err != nil {
-			log.Errorln("NSQ에 보내는 중 오류가 발생하였습니다.")
-			log.Errorln(err)
-			panic(err)
+func ReceiveWaveforms(correlator *packet.Correlator, ser serial.Port, udid string, host string) {
+	response, ok := ReceiveResponse(correlator, ser)
+	if !ok {
+		return
+	}
+
+	online, ok := response.(packet.OnlineValues120)
+	if !ok {
+		return
+	}
+
+	var now = time.Now()
+	for _, sample := range online.Samples() {
+		var model = mq.QueueModel{
+			TIMESTAMP:      now,
+			KEY:            sample.Key,
+			TYPE:           "Waveform",
+			HOST:           host,
+			VALUE_UNIT:     sample.Unit,
+			UDID:           udid,
+			WAVEFORM_VALUE: []float64{sample.Value()},
+		}
+
+		if nomenclature, ok := packet.ChannelNomenclatures[sample.Key]; ok {
+			model.MDC_CODE = nomenclature.Code
+			model.MDC_REFID = nomenclature.RefID
+		}
+
+		if Options.Raw {
+			model.WAVEFORM_RAW = []int{int(sample.Count)}
 		}
+
+		if quality != nil {
+			model.QUALITY = quality.Check(now, sample)
+		}
+
+		Publish(model)
+	}
+
+	PublishStatus(now, online.VentilatorStatus, udid, host)
+
+	var sample = analysis.SampleFrom(now, online)
+	if segmenter != nil {
+		segmenter.Add(sample)
+	}
+
+	if asynchrony != nil {
+		asynchrony.Add(sample)
 	}
 }
 
-func ReceiveNumerics(identifier int, ser serial.Port, udid string, host string) {
-	result, err := ReadFromSerial(ser)
-	if err != nil {
-		log.Errorln("에러가 발생했습니다.")
-		log.Errorln(err)
-		os.Exit(1)
+// Interval마다 요약한 Numeric 값을 "Trend" 타입으로 Trend 토픽에 보냅니다. 값은 평균입니다.
+// 유효한 값이 없던 구간은 버전 2에서 numeric_value 없이 보냅니다. (버전 1은 TREND.COUNT로 구분)
+func PublishTrend(summary analysis.TrendSummary, udid string, host string) {
+	PublishTo([]mq.Sink{
+		mq.NSQSink{Pool: nsqPool, Topic: Options.TrendTopic, Encoder: nsqEncoder, Signer: nsqSigner},
+	}, mq.QueueModel{
+		TIMESTAMP:     summary.End,
+		KEY:           summary.Key,
+		TYPE:          "Trend",
+		HOST:          host,
+		VALUE_UNIT:    summary.Unit,
+		UDID:          udid,
+		NUMERIC_VALUE: summary.Mean,
+		TREND: &mq.TrendStatistics{
+			INTERVAL: summary.Interval.String(),
+			START:    summary.Start,
+			MIN:      summary.Min,
+			MAX:      summary.Max,
+			MEAN:     summary.Mean,
+			MEDIAN:   summary.Median,
+			LAST:     summary.Last,
+			COUNT:    summary.Count,
+			INVALID:  summary.Invalid,
+		},
+	})
+}
+
+// 벤틸레이터 상태가 바뀌면 "Status" 타입으로 보냅니다. 값은 상태 바이트입니다.
+// 흡기/호기 비트는 호흡마다 바뀌고 "Breath" 타입으로 보내므로 비교하지 않습니다.
+func PublishStatus(timestamp time.Time, status byte, udid string, host string) {
+	var masked = int(status &^ (packet.STATUS_INSPIRATION | packet.STATUS_EXPIRATION))
+	if masked == ventilatorStatus {
+		return
 	}
 
-	pkt, err := packet.ParseResponsePacket(result)
-	if err != nil {
-		log.Errorln(result)
-		log.Errorln(pkt)
-		log.Errorln("에러가 발생했습니다.")
-		log.Errorln(err)
-		os.Exit(1)
+	ventilatorStatus = masked
+	Publish(mq.QueueModel{
+		TIMESTAMP:     timestamp,
+		KEY:           "VENTILATOR_STATUS",
+		TYPE:          "Status",
+		HOST:          host,
+		VALUE_UNIT:    "",
+		UDID:          udid,
+		NUMERIC_VALUE: float64(masked),
+	})
+}
+
+// 채널의 신호 품질이 바뀌면 "Diagnostic" 타입으로 보냅니다. 나빠진 경우 값은 1입니다.
+func PublishQuality(event analysis.QualityEvent, udid string, host string) {
+	if event.Degraded() {
+		log.Warnf("%s 채널의 신호 품질이 나빠졌습니다: %s -> %s", event.Key, event.From, event.To)
 	}
 
-	log.Debug("기기에서 전송된 데이터: ")
-	log.Debug(pkt)
-	log.Debug(result)
+	var degraded = float64(0)
+	if event.Degraded() {
+		degraded = 1
+	}
 
-	var strVal = string(pkt.Values)
-	floatVal, err := strconv.ParseFloat(strVal, 64)
+	Publish(mq.QueueModel{
+		TIMESTAMP:     event.Timestamp,
+		KEY:           event.Key,
+		TYPE:          "Diagnostic",
+		HOST:          host,
+		VALUE_UNIT:    "",
+		UDID:          udid,
+		NUMERIC_VALUE: degraded,
+		QUALITY:       event.To,
+	})
+}
 
-	if err != nil {
-		var model = mq.QueueModel{
-			TIMESTAMP:     time.Now(),
-			KEY:           packet.TypeIntString[identifier],
-			TYPE:          "Numeric",
+// 비동기 이벤트를 "Event" 타입으로 보냅니다. 값은 0 ~ 1 사이의 신뢰도입니다.
+func PublishAsynchrony(event analysis.AsynchronyEvent, udid string, host string) {
+	Publish(mq.QueueModel{
+		TIMESTAMP:     event.Timestamp,
+		KEY:           event.Kind,
+		TYPE:          "Event",
+		HOST:          host,
+		VALUE_UNIT:    "",
+		UDID:          udid,
+		NUMERIC_VALUE: event.Confidence,
+	})
+}
+
+// 호흡 하나가 끝날 때마다 계산된 값들을 "Breath" 타입으로 보냅니다.
+func PublishBreath(breath analysis.Breath, udid string, host string) {
+	for _, metric := range breath.Metrics() {
+		Publish(mq.QueueModel{
+			TIMESTAMP:     breath.End,
+			KEY:           metric.Key,
+			TYPE:          "Breath",
 			HOST:          host,
-			VALUE_UNIT:    "",
+			VALUE_UNIT:    metric.Unit,
 			UDID:          udid,
-			NUMERIC_VALUE: floatVal,
-		}
+			NUMERIC_VALUE: metric.Value,
+		})
+	}
+}
 
-		err := mq.SendToNSQ(model, Options.NsqAddress)
-		if err != nil {
-			log.Errorln("NSQ에 보내는 중 오류가 발생하였습니다.")
+// 계산에 사용한 값들과 함께 "Derived" 타입으로 보냅니다.
+func PublishIndicator(indicator analysis.Indicator, udid string, host string) {
+	Publish(mq.QueueModel{
+		TIMESTAMP:     indicator.Timestamp,
+		KEY:           indicator.Key,
+		TYPE:          "Derived",
+		HOST:          host,
+		VALUE_UNIT:    indicator.Unit,
+		UDID:          udid,
+		NUMERIC_VALUE: indicator.Value,
+		SOURCES:       indicator.Sources,
+	})
+}
+
+// 모든 출력으로 보냅니다.
+func Publish(model mq.QueueModel) {
+	PublishTo(sinks, model)
+}
+
+// Session, Sequence, 포트를 붙여서 targets로 보냅니다.
+// NSQ에 보내지 못하면 종료하고, 그 외의 출력은 오류만 남깁니다.
+func PublishTo(targets []mq.Sink, model mq.QueueModel) {
+	model.PORT = Options.Port
+	sequencer.Stamp(&model)
+
+	for _, sink := range targets {
+		if err := sink.Send(model); err != nil {
+			switch sink.(type) {
+			case mq.NSQSink, *mq.BatchSink:
+				log.Errorln("NSQ에 보내는 중 오류가 발생하였습니다.")
+				log.Errorln(err)
+				panic(err)
+			}
+
+			log.Errorln("데이터를 내보내는 중 오류가 발생하였습니다.")
 			log.Errorln(err)
-			panic(err)
 		}
 	}
 }
 
+func ReceiveNumerics(identifier int, correlator *packet.Correlator, ser serial.Port, udid string, host string) {
+	response, ok := ReceiveResponse(correlator, ser)
+	if !ok {
+		return
+	}
+
+	numeric, ok := response.(packet.NumericResponse)
+	if !ok {
+		return
+	}
+
+	// 값을 읽을 수 없는 경우(ex. 측정되지 않음)에는 보내지 않습니다.
+	floatVal, err := numeric.Value()
+	var alarm = packet.IsAlarm(identifier)
+	if trend != nil && !alarm {
+		trend.Add(identifier, floatVal, err == nil, time.Now())
+	}
+
+	if err != nil {
+		log.Debugf("%s의 값을 읽을 수 없습니다: %q", numeric.Name(), numeric.Values)
+		return
+	}
+
+	var now = time.Now()
+	var model = mq.NumericModel(identifier, floatVal, numeric.Unit(), now)
+	model.HOST = host
+	model.UDID = udid
+	Publish(model)
+
+	// 알람 상태는 Trend나 Derived 계산에 쓰지 않습니다.
+	if derived != nil && !alarm {
+		derived.AddNumeric(identifier, floatVal, now)
+	}
+}
+
+// Serial 연결 설정 (Spec 문서 2.1)
+func SerialMode() *serial.Mode {
+	return &serial.Mode{
+		BaudRate: 9600,
+		Parity:   serial.EvenParity,
+		StopBits: serial.TwoStopBits,
+	}
+}
+
 func OpenPort(port string, config *serial.Mode) (socket serial.Port) {
 	defer func() {
 		if r := recover(); r != nil {
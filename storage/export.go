@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Entry를 파일 형식으로 씁니다.
+type Writer interface {
+	Write(entry Entry) error
+	Close() error
+}
+
+// format은 json(Entry의 배열) 또는 csv(값마다 한 줄, Waveform 블록은 샘플마다 한 줄)입니다.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "csv":
+		var writer = csv.NewWriter(w)
+		return &csvWriter{w: writer}, writer.Write(CSVHeader)
+	}
+
+	return nil, fmt.Errorf("Unknown Export Format %q", format)
+}
+
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (writer *jsonWriter) Write(entry Entry) error {
+	var prefix = ",\n"
+	if writer.count == 0 {
+		prefix = "[\n"
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	writer.count++
+	_, err = writer.w.Write(append([]byte(prefix), line...))
+	return err
+}
+
+func (writer *jsonWriter) Close() error {
+	var end = "\n]\n"
+	if writer.count == 0 {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(writer.w, end)
+	return err
+}
+
+var CSVHeader = []string{"timestamp", "udid", "session", "sequence", "type", "key", "unit", "value", "quality"}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (writer *csvWriter) Write(entry Entry) error {
+	var times, values = entry.Points()
+	for i := range values {
+		writer.w.Write([]string{
+			times[i].Format(time.RFC3339Nano),
+			entry.UDID,
+			entry.Session,
+			strconv.FormatUint(entry.Sequence, 10),
+			entry.Type,
+			entry.Key,
+			entry.Unit,
+			strconv.FormatFloat(values[i], 'f', -1, 64),
+			entry.Quality,
+		})
+	}
+
+	return writer.w.Error()
+}
+
+func (writer *csvWriter) Close() error {
+	writer.w.Flush()
+	return writer.w.Error()
+}
+
+// query에 맞는 Entry를 format으로 씁니다.
+func (store *Store) Export(w io.Writer, format string, query Query) error {
+	writer, err := NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Scan(query, writer.Write); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
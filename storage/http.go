@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GET ?udid=&from=&to=&type=&key=&format=json|csv
+// from, to는 RFC3339이며, type과 key는 쉼표로 여러 개를 줄 수 있습니다.
+func (store *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var values = r.URL.Query()
+	var query = Query{UDID: values.Get("udid")}
+
+	for _, bound := range []struct {
+		name string
+		time *time.Time
+	}{{"from", &query.From}, {"to", &query.To}} {
+		if text := values.Get(bound.name); text != "" {
+			parsed, err := time.Parse(time.RFC3339, text)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			*bound.time = parsed
+		}
+	}
+
+	if text := values.Get("type"); text != "" {
+		query.Types = strings.Split(text, ",")
+	}
+
+	if text := values.Get("key"); text != "" {
+		query.Keys = strings.Split(text, ",")
+	}
+
+	var format = values.Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	var tracking = &trackingWriter{ResponseWriter: w}
+	writer, err := NewWriter(tracking, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := store.Scan(query, writer.Write); err != nil {
+		if !tracking.written {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// 이미 200으로 보내기 시작했으므로 연결을 끊어 잘린 응답임을 알립니다.
+		panic(http.ErrAbortHandler)
+	}
+
+	writer.Close()
+}
+
+// 응답 본문을 쓰기 시작했는지 기록합니다.
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/edf"
+	"github.com/Hazealign/biosignal-hamilton-interface/packet"
+)
+
+var ErrNoWaveform = errors.New("No Waveform Recorded")
+
+// 한 Waveform 채널의 샘플들
+type Channel struct {
+	Key    string
+	Unit   string
+	RefID  string
+	Times  []time.Time
+	Values []float64
+}
+
+// 한 기기의 Entry를 Waveform 채널과 어노테이션으로 모은 것
+// Event, Diagnostic, Status 타입과 알람 Identifier(88 ~ 102)의 Numeric 값(값이 바뀔 때만)을 어노테이션으로 씁니다.
+type Recording struct {
+	UDID      string
+	PatientID string
+	Channels  []*Channel
+	Events    []Entry
+
+	channels map[string]*Channel
+	alarms   map[int]float64
+}
+
+func NewRecording(udid string) *Recording {
+	return &Recording{UDID: udid, channels: map[string]*Channel{}, alarms: map[int]float64{}}
+}
+
+func (recording *Recording) Add(entry Entry) {
+	if entry.PatientID != "" {
+		recording.PatientID = entry.PatientID
+	}
+
+	switch {
+	case entry.Type == "Waveform":
+		var channel, ok = recording.channels[entry.Key]
+		if !ok {
+			channel = &Channel{Key: entry.Key, Unit: entry.Unit, RefID: entry.MDCRefID}
+			recording.channels[entry.Key] = channel
+			recording.Channels = append(recording.Channels, channel)
+		}
+
+		times, values := entry.Points()
+		channel.Times = append(channel.Times, times...)
+		channel.Values = append(channel.Values, values...)
+	case entry.Type == "Event", entry.Type == "Diagnostic", entry.Type == "Status":
+		recording.Events = append(recording.Events, entry)
+	case (entry.Type == "Alarm" || entry.Type == "Numeric" && packet.IsAlarm(entry.Identifier)) && entry.NumericValue != nil:
+		// 이름이 같은 Identifier가 있으므로(ex. 53, 91 High Pressure) Identifier로 구분합니다.
+		if last, ok := recording.alarms[entry.Identifier]; !ok || last != *entry.NumericValue {
+			recording.alarms[entry.Identifier] = *entry.NumericValue
+			recording.Events = append(recording.Events, entry)
+		}
+	}
+}
+
+// 샘플 간격의 중앙값으로 추정한 초당 샘플 수 (1 이상의 정수), 기록이 끊긴 구간은 영향을 주지 않습니다.
+func (recording *Recording) Rate() float64 {
+	var intervals = []float64{}
+	for _, channel := range recording.Channels {
+		channel.sort()
+		for i := 1; i < len(channel.Times); i++ {
+			if interval := channel.Times[i].Sub(channel.Times[i-1]).Seconds(); interval > 0 {
+				intervals = append(intervals, interval)
+			}
+		}
+	}
+
+	if len(intervals) == 0 {
+		return 1
+	}
+
+	sort.Float64s(intervals)
+	return math.Max(1, math.Round(1/intervals[len(intervals)/2]))
+}
+
+func (recording *Recording) span() (start time.Time, end time.Time) {
+	for _, channel := range recording.Channels {
+		for _, timestamp := range channel.Times {
+			if start.IsZero() || timestamp.Before(start) {
+				start = timestamp
+			}
+
+			if timestamp.After(end) {
+				end = timestamp
+			}
+		}
+	}
+
+	return start.Truncate(time.Second), end
+}
+
+// 어노테이션 내용
+func (entry Entry) Annotation() string {
+	switch {
+	case entry.Type == "Diagnostic":
+		return fmt.Sprintf("%s quality %s", entry.Key, entry.Quality)
+	case entry.Type == "Status" && entry.NumericValue != nil:
+		return fmt.Sprintf("%s 0x%02X", entry.Key, int(*entry.NumericValue))
+	case entry.NumericValue != nil:
+		return fmt.Sprintf("%s %s", entry.Key, strconv.FormatFloat(*entry.NumericValue, 'f', -1, 64))
+	}
+
+	return entry.Key
+}
+
+// rate(초당 샘플 수, 0이면 추정)로 다시 샘플링해서 duration 길이의 Data Record로 나눈 EDF+ 파일
+// 샘플 사이는 직전 값을 유지하며, 어떤 채널에도 샘플이 없는 Record는 건너뜁니다.
+func (recording *Recording) EDF(rate float64, duration time.Duration) (file edf.File, err error) {
+	if len(recording.Channels) == 0 {
+		return file, ErrNoWaveform
+	}
+
+	if rate <= 0 {
+		rate = recording.Rate()
+	}
+
+	if duration <= 0 {
+		duration = time.Second
+	}
+
+	var samples = int(math.Max(1, math.Round(rate*duration.Seconds())))
+	var start, end = recording.span()
+
+	file = edf.File{
+		Patient:        recording.PatientID + " X X X",
+		Equipment:      "HAMILTON-" + recording.UDID,
+		Start:          start,
+		RecordDuration: duration,
+	}
+
+	if recording.PatientID == "" {
+		file.Patient = ""
+	}
+
+	var indexes = make([]int, len(recording.Channels))
+	for _, channel := range recording.Channels {
+		channel.sort()
+
+		var min, max = math.Inf(1), math.Inf(-1)
+		for _, value := range channel.Values {
+			min, max = math.Min(min, value), math.Max(max, value)
+		}
+
+		min, max = math.Floor(min), math.Ceil(max)
+		if max <= min {
+			max = min + 1
+		}
+
+		file.Signals = append(file.Signals, edf.Signal{
+			Label:       channel.Key,
+			Transducer:  channel.RefID,
+			Unit:        channel.Unit,
+			PhysicalMin: min,
+			PhysicalMax: max,
+			Samples:     samples,
+		})
+	}
+
+	for onset := time.Duration(0); !start.Add(onset).After(end); onset += duration {
+		var from, to = start.Add(onset), start.Add(onset + duration)
+		var record = edf.Record{Onset: onset}
+		var found = false
+
+		for i, channel := range recording.Channels {
+			var data = make([]float64, samples)
+			for j := range data {
+				var at = from.Add(time.Duration(j) * duration / time.Duration(samples))
+				for indexes[i]+1 < len(channel.Times) && !channel.Times[indexes[i]+1].After(at) {
+					indexes[i]++
+				}
+
+				data[j] = channel.Values[indexes[i]]
+			}
+
+			var next = indexes[i]
+			for next < len(channel.Times) && channel.Times[next].Before(from) {
+				next++
+			}
+
+			if next < len(channel.Times) && channel.Times[next].Before(to) {
+				found = true
+			}
+
+			record.Data = append(record.Data, data)
+		}
+
+		if found {
+			file.Records = append(file.Records, record)
+		}
+	}
+
+	for _, event := range recording.Events {
+		file.Annotations = append(file.Annotations, edf.Annotation{Onset: event.Timestamp.Sub(start), Text: event.Annotation()})
+	}
+
+	return file, nil
+}
+
+type byTime Channel
+
+func (channel *byTime) Len() int           { return len(channel.Times) }
+func (channel *byTime) Less(i, j int) bool { return channel.Times[i].Before(channel.Times[j]) }
+func (channel *byTime) Swap(i, j int) {
+	channel.Times[i], channel.Times[j] = channel.Times[j], channel.Times[i]
+	channel.Values[i], channel.Values[j] = channel.Values[j], channel.Values[i]
+}
+
+// 샘플을 시간순으로 정렬합니다. (여러 파일을 읽은 경우 순서가 섞일 수 있습니다.)
+func (channel *Channel) sort() {
+	sort.Stable((*byTime)(channel))
+}
+
+// 채널 하나를 timestamp, 시작부터 지난 초, 값의 CSV로 씁니다.
+func (channel *Channel) WriteCSV(w io.Writer) error {
+	var writer = csv.NewWriter(w)
+	var header = channel.Key
+	if channel.Unit != "" {
+		header += " (" + channel.Unit + ")"
+	}
+
+	writer.Write([]string{"timestamp", "elapsed", header})
+
+	channel.sort()
+	for i := range channel.Times {
+		writer.Write([]string{
+			channel.Times[i].Format(time.RFC3339Nano),
+			strconv.FormatFloat(channel.Times[i].Sub(channel.Times[0]).Seconds(), 'f', -1, 64),
+			strconv.FormatFloat(channel.Values[i], 'f', -1, 64),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,433 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Hazealign/biosignal-hamilton-interface/mq"
+)
+
+var (
+	ErrReadOnly     = errors.New("Store is Read Only")
+	ErrNotDirectory = errors.New("Not a Directory")
+)
+
+// 세그먼트 파일 이름의 시각 형식 (UTC)
+const SEGMENT_LAYOUT = "20060102T150405Z"
+
+const SEGMENT_EXTENSION = ".jsonl"
+
+// 저장하는 한 줄, Waveform은 BlockSize개의 샘플을 하나로 묶고 End에 마지막 샘플의 시각을 넣습니다.
+type Entry struct {
+	mq.Record
+	End *time.Time `json:"end,omitempty"`
+}
+
+// Entry의 값들, Waveform 블록은 Timestamp와 End 사이를 균등하게 나눈 시각을 붙입니다.
+func (entry Entry) Points() (times []time.Time, values []float64) {
+	if entry.NumericValue != nil {
+		return []time.Time{entry.Timestamp}, []float64{*entry.NumericValue}
+	}
+
+	var step = time.Duration(0)
+	if entry.End != nil && len(entry.WaveformValue) > 1 {
+		step = entry.End.Sub(entry.Timestamp) / time.Duration(len(entry.WaveformValue)-1)
+	}
+
+	for i, value := range entry.WaveformValue {
+		times = append(times, entry.Timestamp.Add(time.Duration(i)*step))
+		values = append(values, value)
+	}
+
+	return
+}
+
+type segment struct {
+	start time.Time
+	file  *os.File
+}
+
+// 기기(UDID)마다 Segment 길이의 파일에 JSON 한 줄씩 추가하는 저장소 (<Directory>/<UDID>/<시작 시각>.jsonl)
+// Retention보다 오래된 세그먼트와, 기기별로 MaxBytes를 넘는 오래된 세그먼트는 새 세그먼트를 열 때 지웁니다.
+// 프로세스가 죽어서 마지막 줄이 잘린 경우에는 읽을 때 그 줄만 버립니다.
+type Store struct {
+	Directory string
+	Segment   time.Duration
+	Retention time.Duration
+	MaxBytes  int64
+	BlockSize int
+
+	readOnly bool
+	lock     sync.Mutex
+	segments map[string]*segment
+	blocks   map[string]*Entry
+}
+
+func Open(directory string) (*Store, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		Directory: directory,
+		Segment:   time.Hour,
+		BlockSize: 50,
+		segments:  map[string]*segment{},
+		blocks:    map[string]*Entry{},
+	}, nil
+}
+
+// 읽기만 하는 저장소를 엽니다. 디렉토리가 없으면 만들지 않고 에러를 반환합니다. (records, export)
+func OpenReadOnly(directory string) (*Store, error) {
+	info, err := os.Stat(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s: %w", directory, ErrNotDirectory)
+	}
+
+	store, err := Open(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	store.readOnly = true
+	return store, nil
+}
+
+func (store *Store) Send(d mq.QueueModel) error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+
+	var entry = Entry{Record: d.Record()}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if d.TYPE != "Waveform" || store.BlockSize <= 1 {
+		return store.write(entry)
+	}
+
+	var id = d.UDID + "\x00" + d.KEY
+	var block, ok = store.blocks[id]
+	if !ok {
+		block = &entry
+		store.blocks[id] = block
+	} else {
+		block.WaveformValue = append(block.WaveformValue, entry.WaveformValue...)
+		block.WaveformRaw = append(block.WaveformRaw, entry.WaveformRaw...)
+		block.Quality = entry.Quality
+	}
+
+	var end = entry.Timestamp
+	block.End = &end
+	if len(block.WaveformValue) < store.BlockSize {
+		return nil
+	}
+
+	delete(store.blocks, id)
+	return store.write(*block)
+}
+
+func (store *Store) length() time.Duration {
+	if store.Segment <= 0 {
+		return time.Hour
+	}
+
+	return store.Segment
+}
+
+// 잠금을 잡은 상태에서 호출해야 합니다.
+func (store *Store) write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	var udid = name(entry.UDID)
+	var start = entry.Timestamp.UTC().Truncate(store.length())
+	var current = store.segments[udid]
+	if current == nil || !current.start.Equal(start) {
+		if current != nil {
+			current.file.Close()
+		}
+
+		if err := os.MkdirAll(filepath.Join(store.Directory, udid), 0755); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(filepath.Join(store.Directory, udid, start.Format(SEGMENT_LAYOUT)+SEGMENT_EXTENSION), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+
+		current = &segment{start: start, file: file}
+		store.segments[udid] = current
+		if err := store.prune(udid, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	_, err = current.file.Write(append(line, '\n'))
+	return err
+}
+
+// 기기의 세그먼트 파일들 (오래된 것부터)
+func (store *Store) files(udid string) (starts []time.Time, paths []string, err error) {
+	infos, err := ioutil.ReadDir(filepath.Join(store.Directory, udid))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, info := range infos {
+		start, err := time.Parse(SEGMENT_LAYOUT, strings.TrimSuffix(info.Name(), SEGMENT_EXTENSION))
+		if err != nil || !strings.HasSuffix(info.Name(), SEGMENT_EXTENSION) {
+			continue
+		}
+
+		starts = append(starts, start)
+		paths = append(paths, filepath.Join(store.Directory, udid, info.Name()))
+	}
+
+	return starts, paths, nil
+}
+
+// 잠금을 잡은 상태에서 호출해야 합니다. 지금 쓰고 있는 세그먼트는 지우지 않습니다.
+func (store *Store) prune(udid string, now time.Time) error {
+	starts, paths, err := store.files(udid)
+	if err != nil {
+		return err
+	}
+
+	var sizes = make([]int64, len(paths))
+	var total = int64(0)
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+
+	for i := 0; i < len(paths)-1; i++ {
+		var expired = store.Retention > 0 && now.Sub(starts[i].Add(store.length())) > store.Retention
+		var over = store.MaxBytes > 0 && total > store.MaxBytes
+		if !expired && !over {
+			break
+		}
+
+		if err := os.Remove(paths[i]); err != nil {
+			return err
+		}
+
+		total -= sizes[i]
+	}
+
+	return nil
+}
+
+// 모든 기기에서 now 기준으로 Retention, MaxBytes를 넘는 세그먼트를 지웁니다.
+func (store *Store) Prune(now time.Time) error {
+	devices, err := store.Devices()
+	if err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for _, udid := range devices {
+		if err := store.prune(udid, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 모인 Waveform 블록을 모두 씁니다.
+func (store *Store) Flush() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var ids = []string{}
+	for id := range store.blocks {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := store.write(*store.blocks[id]); err != nil {
+			return err
+		}
+
+		delete(store.blocks, id)
+	}
+
+	return nil
+}
+
+func (store *Store) Close() error {
+	var err = store.Flush()
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	for udid, segment := range store.segments {
+		segment.file.Close()
+		delete(store.segments, udid)
+	}
+
+	return err
+}
+
+// 저장된 기기들
+func (store *Store) Devices() ([]string, error) {
+	infos, err := ioutil.ReadDir(store.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices = []string{}
+	for _, info := range infos {
+		if info.IsDir() {
+			devices = append(devices, info.Name())
+		}
+	}
+
+	return devices, nil
+}
+
+// 읽을 범위, UDID가 비어있으면 모든 기기, Types나 Keys가 비어있으면 모든 타입, 키입니다.
+type Query struct {
+	UDID  string
+	From  time.Time
+	To    time.Time
+	Types []string
+	Keys  []string
+}
+
+func (query Query) Match(entry Entry) bool {
+	var end = entry.Timestamp
+	if entry.End != nil {
+		end = *entry.End
+	}
+
+	if (!query.From.IsZero() && end.Before(query.From)) || (!query.To.IsZero() && !entry.Timestamp.Before(query.To)) {
+		return false
+	}
+
+	return contains(query.Types, entry.Type) && contains(query.Keys, entry.Key)
+}
+
+func contains(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// query에 맞는 Entry를 기기별, 시간순(세그먼트 순서)으로 fn에 넘깁니다. fn이 error를 반환하면 멈춥니다.
+func (store *Store) Scan(query Query, fn func(Entry) error) error {
+	var devices = []string{name(query.UDID)}
+	if query.UDID == "" {
+		var err error
+		if devices, err = store.Devices(); err != nil {
+			return err
+		}
+	}
+
+	for _, udid := range devices {
+		starts, paths, err := store.files(udid)
+		if err != nil {
+			return err
+		}
+
+		for i, path := range paths {
+			if !query.To.IsZero() && !starts[i].Before(query.To) {
+				break
+			}
+
+			// 블록은 첫 샘플의 세그먼트에 들어가므로 한 세그먼트 앞까지 읽습니다.
+			if !query.From.IsZero() && i+2 < len(paths) && !starts[i+2].After(query.From) {
+				continue
+			}
+
+			if err := ScanFile(path, query, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// path의 JSON Lines(세그먼트 파일이나 버전 2 메시지를 한 줄씩 저장한 파일)에서 query에 맞는 Entry를 fn에 넘깁니다.
+func ScanFile(path string, query Query, fn func(Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var reader = bufio.NewReader(file)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil // 마지막 줄이 잘린 경우 버립니다.
+		} else if err != nil {
+			return err
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, number, err)
+		}
+
+		if query.Match(entry) {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 디렉토리 이름으로 쓸 수 없는 문자를 바꿉니다.
+func name(udid string) string {
+	if udid == "" {
+		return "unknown"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '.' || r == 0 {
+			return '_'
+		}
+
+		return r
+	}, udid)
+}
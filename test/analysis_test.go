@@ -0,0 +1,388 @@
+package signalize
+
+import (
+	"math"
+	"time"
+
+	"biosignal-hamilton-interface/analysis"
+	"biosignal-hamilton-interface/packet"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 20Hz로 샘플링한 가상의 호흡 파형
+// 흡기 1초 (30 L/min, 500mL), 정지 0.2초, 호기 1.8초 (500mL), PEEP 5, Plateau 15
+func SyntheticBreaths(start time.Time, count int, trigger bool) []analysis.Sample {
+	var samples = []analysis.Sample{}
+	var at = start
+	var step = 50 * time.Millisecond
+
+	for breath := 0; breath < count; breath++ {
+		for i := 0; i < 60; i++ {
+			var sample = analysis.Sample{Timestamp: at}
+
+			switch {
+			case i < 20:
+				sample.Flow = 30
+				sample.Pressure = 5 + float64(i)
+			case i < 24:
+				sample.Flow = 0
+				sample.Pressure = 15
+			default:
+				sample.Flow = -500.0 / 1000 * 60 / 1.8
+				sample.Pressure = 5
+			}
+
+			if trigger && i == 59 {
+				sample.Flow = 0
+				sample.Pressure = 3
+			}
+
+			samples = append(samples, sample)
+			at = at.Add(step)
+		}
+	}
+
+	return samples
+}
+
+var BreathSegmentation = Describe("Breath Segmentation", func() {
+	It("Mandatory Breaths", func() {
+		var breaths = []analysis.Breath{}
+		var segmenter = analysis.NewSegmenter(func(breath analysis.Breath) {
+			breaths = append(breaths, breath)
+		})
+
+		for _, sample := range SyntheticBreaths(time.Unix(0, 0), 4, false) {
+			segmenter.Add(sample)
+		}
+
+		Ω(breaths).Should(HaveLen(3))
+
+		var breath = breaths[1]
+		Ω(breath.VTi).Should(BeNumerically("~", 500, 30))
+		Ω(breath.VTe).Should(BeNumerically("~", 500, 30))
+		Ω(breath.InspiratoryTime).Should(Equal(1200 * time.Millisecond))
+		Ω(breath.ExpiratoryTime).Should(Equal(1800 * time.Millisecond))
+		Ω(breath.IERatio).Should(BeNumerically("~", 1.5, 0.01))
+		Ω(breath.PeakPressure).Should(BeNumerically("~", 24, 0.01))
+		Ω(breath.HasPlateau).Should(BeTrue())
+		Ω(breath.PlateauPressure).Should(BeNumerically("~", 15, 0.01))
+		Ω(breath.PEEP).Should(BeNumerically("~", 5, 0.01))
+		Ω(breath.Spontaneous).Should(BeFalse())
+	})
+
+	It("Spontaneous Breaths", func() {
+		var breaths = []analysis.Breath{}
+		var segmenter = analysis.NewSegmenter(func(breath analysis.Breath) {
+			breaths = append(breaths, breath)
+		})
+
+		for _, sample := range SyntheticBreaths(time.Unix(0, 0), 4, true) {
+			segmenter.Add(sample)
+		}
+
+		Ω(breaths).Should(HaveLen(3))
+		Ω(breaths[2].Spontaneous).Should(BeTrue())
+	})
+
+	It("Phase from Ventilator Status with Flow Fallback", func() {
+		var segment = func(samples []analysis.Sample) analysis.Breath {
+			var breaths = []analysis.Breath{}
+			var segmenter = analysis.NewSegmenter(func(breath analysis.Breath) {
+				breaths = append(breaths, breath)
+			})
+			segmenter.PhaseFromStatus = analysis.PhaseFromVentilatorStatus
+
+			for _, sample := range samples {
+				segmenter.Add(sample)
+			}
+
+			Ω(breaths).Should(HaveLen(3))
+			return breaths[1]
+		}
+
+		// 장비는 Flow가 멈춘 뒤에도 1.5초까지 흡기로 알려줍니다.
+		var samples = SyntheticBreaths(time.Unix(0, 0), 4, false)
+		for i := range samples {
+			if i%60 < 30 {
+				samples[i].Status = packet.STATUS_INSPIRATION
+			} else {
+				samples[i].Status = packet.STATUS_EXPIRATION
+			}
+		}
+
+		Ω(segment(samples).InspiratoryTime).Should(Equal(1500 * time.Millisecond))
+
+		// 상태 비트가 없으면 Flow의 부호로 판단합니다.
+		for i := range samples {
+			samples[i].Status = 0
+		}
+
+		Ω(segment(samples).InspiratoryTime).Should(Equal(1200 * time.Millisecond))
+		Ω(analysis.PhaseFromVentilatorStatus(packet.STATUS_INSPIRATION | packet.STATUS_EXPIRATION)).Should(Equal(analysis.PHASE_UNKNOWN))
+	})
+})
+
+var DerivedIndicators = Describe("Derived Indicators", func() {
+	It("From Numerics", func() {
+		var indicators = map[string]analysis.Indicator{}
+		var engine = analysis.NewDerivedEngine(func(indicator analysis.Indicator) {
+			indicators[indicator.Key] = indicator
+		})
+
+		var now = time.Unix(0, 0)
+		engine.AddNumeric(63, 30, now)
+		engine.AddNumeric(64, 15, now)
+		engine.AddNumeric(77, 300, now)
+
+		Ω(indicators["RSBI"].Value).Should(BeNumerically("~", 100, 1e-9))
+		Ω(indicators["RSBI"].Sources).Should(Equal([]string{"63 f total", "77 VT Insp spont"}))
+		Ω(indicators["SPONT_RATIO"].Value).Should(BeNumerically("~", 50, 1e-9))
+
+		engine.AddNumeric(115, 1, now)
+		engine.AddNumeric(115, 2, now.Add(20*time.Second))
+		engine.AddNumeric(115, 3, now.Add(40*time.Second))
+
+		Ω(indicators["P01_TREND"].Value).Should(BeNumerically("~", 3, 1e-9))
+	})
+
+	It("Stale Numerics", func() {
+		var indicators = map[string]analysis.Indicator{}
+		var engine = analysis.NewDerivedEngine(func(indicator analysis.Indicator) {
+			indicators[indicator.Key] = indicator
+		})
+
+		engine.AddNumeric(63, 30, time.Unix(0, 0))
+		engine.AddNumeric(77, 300, time.Unix(0, 0).Add(time.Hour))
+
+		Ω(indicators).ShouldNot(HaveKey("RSBI"))
+	})
+
+	It("From Breaths", func() {
+		var indicators = map[string]analysis.Indicator{}
+		var engine = analysis.NewDerivedEngine(func(indicator analysis.Indicator) {
+			indicators[indicator.Key] = indicator
+		})
+
+		var segmenter = analysis.NewSegmenter(engine.AddBreath)
+		for _, sample := range SyntheticBreaths(time.Unix(0, 0), 6, false) {
+			segmenter.Add(sample)
+		}
+
+		// 3초마다 500mL: 20 b/min, 0.5 L
+		Ω(indicators["RSBI_WAVEFORM"].Value).Should(BeNumerically("~", 40, 3))
+		Ω(indicators["SPONT_RATIO_WAVEFORM"].Value).Should(BeZero())
+	})
+})
+
+var AsynchronyDetection = Describe("Asynchrony Detection", func() {
+	var run = func(samples []analysis.Sample) ([]analysis.AsynchronyEvent, []analysis.AsynchronyIndex) {
+		var events = []analysis.AsynchronyEvent{}
+		var indexes = []analysis.AsynchronyIndex{}
+		var detector = analysis.NewAsynchronyDetector(func(event analysis.AsynchronyEvent) {
+			events = append(events, event)
+		}, func(index analysis.AsynchronyIndex) {
+			indexes = append(indexes, index)
+		})
+
+		var segmenter = analysis.NewSegmenter(detector.AddBreath)
+		for _, sample := range samples {
+			segmenter.Add(sample)
+			detector.Add(sample)
+		}
+
+		return events, indexes
+	}
+
+	It("Synchronous Breaths", func() {
+		events, indexes := run(SyntheticBreaths(time.Unix(0, 0), 25, false))
+
+		Ω(events).Should(BeEmpty())
+		Ω(indexes).Should(HaveLen(1))
+		Ω(indexes[0].Breaths).Should(Equal(19))
+		Ω(indexes[0].Index).Should(BeZero())
+	})
+
+	It("Ineffective Effort", func() {
+		var samples = SyntheticBreaths(time.Unix(0, 0), 25, false)
+		samples[5*60+40].Flow = 0.5
+		samples[5*60+41].Flow = 0.5
+
+		events, indexes := run(samples)
+
+		Ω(events).Should(HaveLen(1))
+		Ω(events[0].Kind).Should(Equal(analysis.ASYNC_INEFFECTIVE_EFFORT))
+		Ω(events[0].Timestamp).Should(Equal(samples[5*60+40].Timestamp))
+		Ω(events[0].Confidence).Should(BeNumerically(">", 0.5))
+		Ω(indexes[0].Index).Should(BeNumerically("~", 100.0/20, 0.01))
+	})
+
+	It("Double Trigger", func() {
+		var samples = SyntheticBreaths(time.Unix(0, 0), 25, false)
+
+		// 10번째 호흡의 호기를 0.2초만에 끝내고 다시 흡기
+		for i := 10*60 + 28; i < 11*60; i++ {
+			samples[i].Flow = 30
+		}
+
+		events, _ := run(samples)
+
+		var kinds = []string{}
+		for _, event := range events {
+			kinds = append(kinds, event.Kind)
+		}
+
+		Ω(kinds).Should(ContainElement(analysis.ASYNC_DOUBLE_TRIGGER))
+		Ω(kinds).Should(ContainElement(analysis.ASYNC_LATE_CYCLING))
+	})
+})
+
+var SignalQuality = Describe("Signal Quality", func() {
+	var sample = func(count uint16) packet.ChannelSample {
+		return packet.ChannelSample{Calibration: packet.Calibrations["FLOW"], Count: count}
+	}
+
+	It("Flatline and Recovery", func() {
+		var events = []analysis.QualityEvent{}
+		var monitor = analysis.NewSignalQuality(func(event analysis.QualityEvent) {
+			events = append(events, event)
+		})
+
+		var at = time.Unix(0, 0)
+		for i := 0; i < 120; i++ {
+			var count = uint16(2048)
+			if i < 5 {
+				count += uint16(i)
+			}
+
+			Ω(monitor.Check(at, sample(count))).ShouldNot(BeEmpty())
+			at = at.Add(50 * time.Millisecond)
+		}
+
+		Ω(events).Should(HaveLen(1))
+		Ω(events[0].To).Should(Equal(analysis.QUALITY_FLATLINE))
+		Ω(events[0].Degraded()).Should(BeTrue())
+
+		for i := 0; i < 3; i++ {
+			monitor.Check(at, sample(2100+uint16(i)))
+			at = at.Add(50 * time.Millisecond)
+		}
+
+		Ω(events).Should(HaveLen(2))
+		Ω(events[1].To).Should(Equal(analysis.QUALITY_GOOD))
+	})
+
+	It("Saturation, Jump and Missing Samples", func() {
+		var monitor = analysis.NewSignalQuality(nil)
+		monitor.Hysteresis = 1
+		var at = time.Unix(0, 0)
+
+		Ω(monitor.Check(at, sample(2048))).Should(Equal(analysis.QUALITY_INACTIVE))
+		Ω(monitor.Check(at.Add(50*time.Millisecond), sample(4095))).Should(Equal(analysis.QUALITY_SATURATED))
+		Ω(monitor.Check(at.Add(100*time.Millisecond), sample(2000))).Should(Equal(analysis.QUALITY_JUMP))
+		Ω(monitor.Check(at.Add(2*time.Second), sample(2010))).Should(Equal(analysis.QUALITY_MISSING))
+		Ω(monitor.Check(at.Add(2050*time.Millisecond), sample(2020))).Should(Equal(analysis.QUALITY_GOOD))
+	})
+
+	It("Late Replies Not Flipping Quality", func() {
+		var events = []analysis.QualityEvent{}
+		var monitor = analysis.NewSignalQuality(func(event analysis.QualityEvent) {
+			events = append(events, event)
+		})
+
+		// 샘플 간격(100ms)에서 MaxGap을 정합니다.
+		var at = time.Unix(0, 0)
+		var check = func(gap time.Duration) string {
+			at = at.Add(gap)
+			return monitor.Check(at, sample(2048+uint16(at.UnixNano()/int64(time.Millisecond)%7)))
+		}
+
+		for i := 0; i < 20; i++ {
+			check(100 * time.Millisecond)
+		}
+
+		// 늦은 응답을 다시 읽느라 늦어지거나, 한 번 끊긴 것으로는 바뀌지 않습니다.
+		Ω(check(400 * time.Millisecond)).Should(Equal(analysis.QUALITY_GOOD))
+		Ω(check(time.Second)).Should(Equal(analysis.QUALITY_GOOD))
+		Ω(check(100 * time.Millisecond)).Should(Equal(analysis.QUALITY_GOOD))
+		Ω(events).Should(BeEmpty())
+
+		// 계속 끊기면 MISSING이 됩니다.
+		for i := 0; i < 3; i++ {
+			check(time.Second)
+		}
+
+		Ω(events).Should(HaveLen(1))
+		Ω(events[0].To).Should(Equal(analysis.QUALITY_MISSING))
+	})
+
+	It("Never Active Channel", func() {
+		var events = []analysis.QualityEvent{}
+		var monitor = analysis.NewSignalQuality(func(event analysis.QualityEvent) {
+			events = append(events, event)
+		})
+
+		var at = time.Unix(0, 0)
+		for i := 0; i < 200; i++ {
+			Ω(monitor.Check(at, sample(2048))).Should(Equal(analysis.QUALITY_INACTIVE))
+			at = at.Add(50 * time.Millisecond)
+		}
+
+		Ω(events).Should(BeEmpty())
+	})
+})
+
+var TrendAggregation = Describe("Trend Aggregation", func() {
+	It("Summaries per Interval", func() {
+		var summaries = []analysis.TrendSummary{}
+		var aggregator = analysis.NewTrendAggregator([]time.Duration{time.Minute, 15 * time.Minute}, func(summary analysis.TrendSummary) {
+			summaries = append(summaries, summary)
+		})
+
+		var start = time.Unix(0, 0)
+		for i, value := range []float64{5, 1, 3, 2} {
+			aggregator.Add(43, value, true, start.Add(time.Duration(i)*10*time.Second))
+		}
+
+		aggregator.Add(43, 0, false, start.Add(40*time.Second))
+		aggregator.Add(43, 7, true, start.Add(70*time.Second))
+
+		Ω(summaries).Should(HaveLen(1))
+		Ω(summaries[0].Key).Should(Equal("Tidal Volume"))
+		Ω(summaries[0].Unit).Should(Equal("ml"))
+		Ω(summaries[0].Interval).Should(Equal(time.Minute))
+		Ω(summaries[0].Min).Should(Equal(float64(1)))
+		Ω(summaries[0].Max).Should(Equal(float64(5)))
+		Ω(summaries[0].Mean).Should(Equal(float64(2.75)))
+		Ω(summaries[0].Median).Should(Equal(float64(2.5)))
+		Ω(summaries[0].Last).Should(Equal(float64(2)))
+		Ω(summaries[0].Count).Should(Equal(4))
+		Ω(summaries[0].Invalid).Should(Equal(1))
+
+		aggregator.Flush(start.Add(15 * time.Minute))
+
+		Ω(summaries).Should(HaveLen(3))
+		Ω(summaries[1].Interval).Should(Equal(time.Minute))
+		Ω(summaries[1].Count).Should(Equal(1))
+		Ω(summaries[2].Interval).Should(Equal(15 * time.Minute))
+		Ω(summaries[2].Count).Should(Equal(5))
+		Ω(summaries[2].Last).Should(Equal(float64(7)))
+	})
+
+	It("No Valid Data", func() {
+		var summaries = []analysis.TrendSummary{}
+		var aggregator = analysis.NewTrendAggregator([]time.Duration{time.Minute}, func(summary analysis.TrendSummary) {
+			summaries = append(summaries, summary)
+		})
+
+		aggregator.Add(35, 0, false, time.Unix(0, 0))
+		aggregator.Add(35, math.NaN(), true, time.Unix(10, 0))
+		aggregator.Flush(time.Unix(60, 0))
+
+		Ω(summaries).Should(HaveLen(1))
+		Ω(summaries[0].Count).Should(BeZero())
+		Ω(summaries[0].Invalid).Should(Equal(2))
+	})
+})
@@ -0,0 +1,324 @@
+package signalize
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"biosignal-hamilton-interface/metrics"
+	"biosignal-hamilton-interface/mq"
+
+	"github.com/bitly/go-nsq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 받은 메시지를 기록하고, Down이면 실패하는 가짜 nsqd
+type FakePublisher struct {
+	lock      sync.Mutex
+	Down      bool
+	Published map[string][][]byte
+	Batches   int
+	Delays    []time.Duration
+	Slow      chan struct{} // 있으면 닫힐 때까지 보내지 않습니다.
+}
+
+func (publisher *FakePublisher) fail() error {
+	if publisher.Down {
+		return errors.New("connection refused")
+	}
+
+	return nil
+}
+
+func (publisher *FakePublisher) Publish(topic string, body []byte) error {
+	return publisher.MultiPublish(topic, [][]byte{body})
+}
+
+func (publisher *FakePublisher) MultiPublish(topic string, body [][]byte) error {
+	if publisher.Slow != nil {
+		<-publisher.Slow
+	}
+
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+
+	if err := publisher.fail(); err != nil {
+		return err
+	}
+
+	if publisher.Published == nil {
+		publisher.Published = map[string][][]byte{}
+	}
+
+	publisher.Published[topic] = append(publisher.Published[topic], body...)
+	publisher.Batches++
+	return nil
+}
+
+func (publisher *FakePublisher) DeferredPublish(topic string, delay time.Duration, body []byte) error {
+	publisher.lock.Lock()
+	publisher.Delays = append(publisher.Delays, delay)
+	publisher.lock.Unlock()
+
+	return publisher.Publish(topic, body)
+}
+
+func (publisher *FakePublisher) Ping() error {
+	return publisher.fail()
+}
+
+func (publisher *FakePublisher) Stop() {}
+
+func (publisher *FakePublisher) Count(topic string) int {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+
+	return len(publisher.Published[topic])
+}
+
+func (publisher *FakePublisher) BatchCount() int {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+
+	return publisher.Batches
+}
+
+// 주소별 가짜 nsqd를 쓰는 BrokerPool
+func FakePool(addresses ...string) (*mq.BrokerPool, map[string]*FakePublisher) {
+	var publishers = map[string]*FakePublisher{}
+	for _, address := range addresses {
+		publishers[address] = &FakePublisher{}
+	}
+
+	var pool = mq.NewBrokerPool(addresses, nil)
+	pool.Dial = func(address string, config *nsq.Config) (mq.Publisher, error) {
+		if publisher, ok := publishers[address]; ok {
+			return publisher, nil
+		}
+
+		return nil, fmt.Errorf("unknown %s", address)
+	}
+
+	return pool, publishers
+}
+
+var NSQBrokerPool = Describe("NSQ Broker Pool", func() {
+	It("Failover to the Next nsqd", func() {
+		pool, publishers := FakePool("nsqd-1:4150", "nsqd-2:4150")
+		defer pool.Stop()
+
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("1"))).Should(BeNil())
+		Ω(publishers["nsqd-1:4150"].Count("Biosignal")).Should(Equal(1))
+
+		publishers["nsqd-1:4150"].Down = true
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("2"))).Should(BeNil())
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("3"))).Should(BeNil())
+		Ω(publishers["nsqd-2:4150"].Count("Biosignal")).Should(Equal(2))
+
+		var health = pool.Health()
+		Ω(health[0].Healthy).Should(BeFalse())
+		Ω(health[0].Failures).Should(Equal(uint64(1)))
+		Ω(health[1].Published).Should(Equal(uint64(2)))
+
+		// Ping이 성공하면 다시 먼저 사용합니다.
+		publishers["nsqd-1:4150"].Down = false
+		pool.Check()
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("4"))).Should(BeNil())
+		Ω(publishers["nsqd-1:4150"].Count("Biosignal")).Should(Equal(2))
+
+		publishers["nsqd-1:4150"].Down = true
+		publishers["nsqd-2:4150"].Down = true
+		err := pool.Publish([]string{"Biosignal"}, []byte("5"))
+		Ω(errors.Is(err, mq.ErrNoBroker)).Should(BeTrue())
+	})
+
+	It("Waiting RetryAfter Again after Failed Retry", func() {
+		pool, publishers := FakePool("nsqd-1:4150", "nsqd-2:4150")
+		defer pool.Stop()
+
+		pool.FanOut = true
+		pool.RetryAfter = 50 * time.Millisecond
+		publishers["nsqd-1:4150"].Down = true
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("1"))).Should(BeNil())
+		Ω(pool.Health()[0].Failures).Should(Equal(uint64(1)))
+
+		time.Sleep(60 * time.Millisecond)
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("2"))).Should(BeNil())
+		Ω(pool.Health()[0].Failures).Should(Equal(uint64(2)))
+
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("3"))).Should(BeNil())
+		Ω(pool.Health()[0].Failures).Should(Equal(uint64(2)))
+		Ω(publishers["nsqd-2:4150"].Count("Biosignal")).Should(Equal(3))
+	})
+
+	It("Slow nsqd Not Blocking Others", func() {
+		pool, publishers := FakePool("nsqd-1:4150", "nsqd-2:4150")
+		defer pool.Stop()
+
+		pool.FanOut = true
+		publishers["nsqd-1:4150"].Slow = make(chan struct{})
+		var done = make(chan error)
+		go func() { done <- pool.Publish([]string{"Biosignal"}, []byte("1")) }()
+
+		// 느린 nsqd에 보내는 동안에도 상태를 보거나 Check할 수 있습니다.
+		Eventually(func() int { return len(pool.Health()) }).Should(Equal(2))
+		pool.Check()
+
+		close(publishers["nsqd-1:4150"].Slow)
+		Eventually(done).Should(Receive(BeNil()))
+		Ω(publishers["nsqd-2:4150"].Count("Biosignal")).Should(Equal(1))
+	})
+
+	It("Watching with Zero Interval", func() {
+		pool, publishers := FakePool("nsqd-1:4150")
+		defer pool.Stop()
+
+		publishers["nsqd-1:4150"].Down = true
+		pool.Publish([]string{"Biosignal"}, []byte("1"))
+		publishers["nsqd-1:4150"].Down = false
+
+		// 0이면 기본 간격을 쓰고, 처음 한 번은 바로 Check합니다.
+		Ω(func() { pool.Watch(0, nil) }).ShouldNot(Panic())
+		Eventually(func() bool { return pool.Health()[0].Healthy }).Should(BeTrue())
+	})
+
+	It("Fan Out to All nsqd", func() {
+		pool, publishers := FakePool("nsqd-1:4150", "nsqd-2:4150")
+		defer pool.Stop()
+
+		pool.FanOut = true
+		publishers["nsqd-2:4150"].Down = true
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("1"))).Should(BeNil())
+
+		publishers["nsqd-2:4150"].Down = false
+		pool.RetryAfter = 0
+		Ω(pool.Publish([]string{"Biosignal"}, []byte("2"))).Should(BeNil())
+
+		Ω(publishers["nsqd-1:4150"].Count("Biosignal")).Should(Equal(2))
+		Ω(publishers["nsqd-2:4150"].Count("Biosignal")).Should(Equal(1))
+	})
+
+	It("Discovering nsqd from nsqlookupd", func() {
+		var lookupd = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Ω(r.URL.Path).Should(Equal("/nodes"))
+			w.Write([]byte(`{"producers":[{"broadcast_address":"nsqd-2","tcp_port":4150},{"broadcast_address":"nsqd-3","tcp_port":4150}]}`))
+		}))
+		defer lookupd.Close()
+
+		pool, _ := FakePool("nsqd-2:4150")
+		defer pool.Stop()
+
+		pool.Lookupd = []string{lookupd.URL}
+		Ω(pool.Discover()).Should(BeNil())
+
+		var health = pool.Health()
+		Ω(health).Should(HaveLen(2))
+		Ω(health[0].Discovered).Should(BeFalse())
+		Ω(health[1].Address).Should(Equal("nsqd-3:4150"))
+		Ω(health[1].Discovered).Should(BeTrue())
+	})
+
+	It("Failing on nsqlookupd Error Status", func() {
+		var lookupd = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"producers":[{"broadcast_address":"nsqd-3","tcp_port":4150}]}`))
+		}))
+		defer lookupd.Close()
+
+		pool, _ := FakePool("nsqd-2:4150")
+		defer pool.Stop()
+
+		pool.Lookupd = []string{lookupd.URL}
+		Ω(pool.Discover()).ShouldNot(BeNil())
+		Ω(pool.Health()).Should(HaveLen(1))
+	})
+
+	It("Batching Messages with MPUB", func() {
+		pool, publishers := FakePool("nsqd-1:4150")
+		defer pool.Stop()
+
+		var reports = make(chan mq.BatchReport, 10)
+		var sink = mq.NewBatchSink(mq.NSQSink{Pool: pool, Topic: "Biosignal"}, 3, 0, time.Hour)
+		sink.OnBatch = func(report mq.BatchReport) { reports <- report }
+
+		for i := 0; i < 4; i++ {
+			Ω(sink.Send(mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", WAVEFORM_VALUE: []float64{float64(i)}})).Should(BeNil())
+		}
+
+		// 3개가 차면 바로 보내고, 남은 1개는 Close에서 보냅니다.
+		Ω(publishers["nsqd-1:4150"].Count("Biosignal")).Should(Equal(3))
+		Ω(sink.Close()).Should(BeNil())
+		Ω(publishers["nsqd-1:4150"].Count("Biosignal")).Should(Equal(4))
+		Ω(publishers["nsqd-1:4150"].BatchCount()).Should(Equal(2))
+
+		var report = <-reports
+		Ω(report.Topic).Should(Equal("Biosignal"))
+		Ω(report.Count).Should(Equal(3))
+		Ω(report.Err).Should(BeNil())
+
+		Ω(sink.Send(mq.QueueModel{TYPE: "Numeric"})).Should(Equal(mq.ErrSinkClosed))
+	})
+
+	It("Flushing Batches Every Window", func() {
+		pool, publishers := FakePool("nsqd-1:4150")
+		defer pool.Stop()
+
+		var sink = mq.NewBatchSink(mq.NSQSink{Pool: pool, Topic: "Biosignal"}, 100, 0, 10*time.Millisecond)
+		defer sink.Close()
+
+		Ω(sink.Send(mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP"})).Should(BeNil())
+		Eventually(func() int { return publishers["nsqd-1:4150"].Count("Biosignal") }).Should(Equal(1))
+	})
+
+	It("Deferred Publishing", func() {
+		pool, publishers := FakePool("nsqd-1:4150")
+		defer pool.Stop()
+
+		var sink = mq.NSQSink{Pool: pool, Topic: "Biosignal", Defer: 5 * time.Second}
+		Ω(sink.Send(mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP"})).Should(BeNil())
+		Ω(publishers["nsqd-1:4150"].Delays).Should(Equal([]time.Duration{5 * time.Second}))
+	})
+
+	It("Histogram in Prometheus Text Format", func() {
+		var histogram = metrics.NewHistogram(0.01, 0.1)
+		histogram.Observe(0.005)
+		histogram.Observe(0.05)
+
+		var registry = &metrics.Registry{}
+		registry.Register(metrics.Metric{
+			Name:    "signalize_nsq_batch_latency_seconds",
+			Help:    "Time taken by one MPUB",
+			Type:    metrics.HISTOGRAM,
+			Collect: func() []metrics.Sample { return histogram.Samples(nil) },
+		})
+
+		var buffer = bytes.Buffer{}
+		registry.WriteTo(&buffer)
+		Ω(buffer.String()).Should(ContainSubstring("signalize_nsq_batch_latency_seconds_bucket{le=\"0.01\"} 1\n"))
+		Ω(buffer.String()).Should(ContainSubstring("signalize_nsq_batch_latency_seconds_bucket{le=\"+Inf\"} 2\n"))
+		Ω(buffer.String()).Should(ContainSubstring("signalize_nsq_batch_latency_seconds_count 2\n"))
+	})
+
+	It("Prometheus Text Format", func() {
+		var registry = &metrics.Registry{}
+		registry.Register(metrics.Metric{
+			Name: "signalize_nsq_broker_up",
+			Help: "Whether the nsqd is healthy",
+			Type: metrics.GAUGE,
+			Collect: func() []metrics.Sample {
+				return []metrics.Sample{{Labels: map[string]string{"address": `nsqd "1"`}, Value: 1}}
+			},
+		})
+
+		var buffer = bytes.Buffer{}
+		registry.WriteTo(&buffer)
+		Ω(buffer.String()).Should(Equal("# HELP signalize_nsq_broker_up Whether the nsqd is healthy\n" +
+			"# TYPE signalize_nsq_broker_up gauge\n" +
+			"signalize_nsq_broker_up{address=\"nsqd \\\"1\\\"\"} 1\n"))
+	})
+})
@@ -0,0 +1,135 @@
+package signalize
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"time"
+
+	"biosignal-hamilton-interface/edf"
+	"biosignal-hamilton-interface/mq"
+	"biosignal-hamilton-interface/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func mqRecord(timestamp time.Time, kind string, key string, unit string, value *float64, waveform []float64) mq.Record {
+	return mq.Record{SchemaVersion: 2, Timestamp: timestamp, Type: kind, Key: key, UDID: "abc", Unit: unit, NumericValue: value, WaveformValue: waveform}
+}
+
+var EDFExport = Describe("EDF+ and CSV Export", func() {
+	var start = time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	// 10Hz로 2초 기록하고, 3초 쉬었다가 1초 더 기록합니다.
+	var recording = func() *storage.Recording {
+		var recording = storage.NewRecording("abc")
+		for _, offset := range []time.Duration{0, 5 * time.Second} {
+			var count = 20
+			if offset > 0 {
+				count = 10
+			}
+
+			for i := 0; i < count; i++ {
+				var timestamp = start.Add(offset + time.Duration(i)*100*time.Millisecond)
+				var value = float64(i)
+				recording.Add(storage.Entry{Record: mqRecord(timestamp, "Waveform", "FLOW", "l/min", nil, []float64{value})})
+				recording.Add(storage.Entry{Record: mqRecord(timestamp, "Waveform", "P_PATIENT", "cmH2O", nil, []float64{value / 2})})
+			}
+		}
+
+		var one = float64(1)
+		var diagnostic = mqRecord(start.Add(1500*time.Millisecond), "Diagnostic", "FLOW", "", &one, nil)
+		diagnostic.Quality = "FLAT"
+		recording.Add(storage.Entry{Record: diagnostic})
+		recording.Add(storage.Entry{Record: mqRecord(start.Add(5*time.Second), "Event", "DOUBLE_TRIGGER", "", &one, nil)})
+		return recording
+	}
+
+	It("Writing EDF+ with Annotations and Gaps", func() {
+		var recording = recording()
+		Ω(recording.Rate()).Should(Equal(float64(10)))
+
+		file, err := recording.EDF(0, time.Second)
+		Ω(err).Should(BeNil())
+		Ω(file.Records).Should(HaveLen(3))
+		Ω(file.Records[2].Onset).Should(Equal(5 * time.Second))
+		Ω(file.Records[0].Data[0][3]).Should(Equal(float64(3)))
+
+		var buffer = bytes.Buffer{}
+		_, err = file.WriteTo(&buffer)
+		Ω(err).Should(BeNil())
+
+		var raw = buffer.Bytes()
+		var header = string(raw[:256])
+		Ω(header[:8]).Should(Equal("0       "))
+		Ω(header[88:168]).Should(HavePrefix("Startdate 19-OCT-2026 X X HAMILTON-abc"))
+		Ω(header[168:184]).Should(Equal("19.10.2609.00.00"))
+		Ω(strings.TrimSpace(header[192:236])).Should(Equal("EDF+D"))
+		Ω(strings.TrimSpace(header[236:244])).Should(Equal("3"))
+		Ω(strings.TrimSpace(header[252:256])).Should(Equal("3"))
+
+		var signals = string(raw[256 : 256*4])
+		Ω(signals[:48]).Should(Equal("FLOW            P_PATIENT       EDF Annotations "))
+
+		// Record마다 FLOW 10개, P_PATIENT 10개 다음에 시간 기록 TAL과 어노테이션이 들어갑니다.
+		var size, _ = strconv.Atoi(strings.TrimSpace(signals[216*3+16 : 216*3+24]))
+		var record = raw[256*4 : 256*4+40+size*2]
+		var flow = int16(binary.LittleEndian.Uint16(record[6:8]))
+		Ω(float64(flow)).Should(BeNumerically("~", -32768+3.0/19*65535, 1))
+		Ω(string(record[40:])).Should(HavePrefix("+0\x14\x14\x00\x00"))
+
+		// 1.5초의 어노테이션은 두 번째 Record에 들어갑니다.
+		record = raw[256*4+len(record) : 256*4+2*len(record)]
+		Ω(string(record[40:])).Should(HavePrefix("+1\x14\x14\x00+1.5\x14FLOW quality FLAT\x14\x00"))
+
+		var last = raw[len(raw)-size*2:]
+		Ω(string(last)).Should(HavePrefix("+5\x14\x14\x00+5\x14DOUBLE_TRIGGER 1\x14\x00"))
+	})
+
+	It("Annotating Alarms and Status Changes", func() {
+		var recording = storage.NewRecording("abc")
+		for i, value := range []float64{0, 1, 1, 0} {
+			var value = value
+			var alarm = mqRecord(start.Add(time.Duration(i)*time.Second), "Alarm", "Apnea", "", &value, nil)
+			alarm.Identifier = 94
+			recording.Add(storage.Entry{Record: alarm})
+		}
+
+		// 이름이 같아도 알람 한계(53)는 어노테이션으로 넣지 않습니다.
+		var limit = float64(40)
+		var setting = mqRecord(start, "Numeric", "High Pressure", "cmH2O", &limit, nil)
+		setting.Identifier = 53
+		recording.Add(storage.Entry{Record: setting})
+
+		var status = float64(4)
+		recording.Add(storage.Entry{Record: mqRecord(start.Add(2*time.Second), "Status", "VENTILATOR_STATUS", "", &status, nil)})
+
+		var annotations = []string{}
+		for _, event := range recording.Events {
+			annotations = append(annotations, event.Annotation())
+		}
+
+		Ω(annotations).Should(Equal([]string{"Apnea 0", "Apnea 1", "Apnea 0", "VENTILATOR_STATUS 0x04"}))
+	})
+
+	It("Writing CSV per Channel", func() {
+		var recording = recording()
+		var buffer = bytes.Buffer{}
+		Ω(recording.Channels[1].WriteCSV(&buffer)).Should(BeNil())
+
+		var lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		Ω(lines).Should(HaveLen(31))
+		Ω(lines[0]).Should(Equal("timestamp,elapsed,P_PATIENT (cmH2O)"))
+		Ω(lines[2]).Should(Equal("2026-10-19T09:00:00.1Z,0.1,0.5"))
+	})
+
+	It("No Records", func() {
+		_, err := edf.File{}.WriteTo(&bytes.Buffer{})
+		Ω(err).Should(Equal(edf.ErrNoRecord))
+
+		_, err = storage.NewRecording("abc").EDF(0, time.Second)
+		Ω(err).Should(Equal(storage.ErrNoWaveform))
+	})
+})
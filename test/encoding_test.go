@@ -0,0 +1,124 @@
+package signalize
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var encodingModel = mq.QueueModel{
+	TIMESTAMP:      time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC),
+	TYPE:           "Waveform",
+	KEY:            "FLOW",
+	MDC_CODE:       151764,
+	MDC_REFID:      "MDC_FLOW_AWAY",
+	HOST:           "10.0.0.1:/dev/ttyUSB0",
+	PORT:           "/dev/ttyUSB0",
+	VALUE_UNIT:     "L/min",
+	UDID:           "0123456789abcdef0123456789abcdef01234567",
+	WAVEFORM_VALUE: []float64{12.5, -3.25, 0, 40.1},
+	WAVEFORM_RAW:   []int{2173, 2015, 2048, 2449},
+	QUALITY:        "GOOD",
+	SESSION:        "0011223344556677",
+	SEQUENCE:       42,
+}
+
+var BinaryEncoding = Describe("Binary Encoding", func() {
+	It("Protobuf Round Trip", func() {
+		var trend = encodingModel
+		trend.TYPE = "Trend"
+		trend.SOURCES = []string{"63 f total"}
+		trend.TREND = &mq.TrendStatistics{INTERVAL: "1m0s", START: encodingModel.TIMESTAMP, MIN: 1, MAX: 3, MEAN: 2, MEDIAN: 2, LAST: 3, COUNT: 3, INVALID: 1}
+
+		for _, model := range []mq.QueueModel{encodingModel, trend} {
+			body, err := mq.ProtobufEncoder{}.Encode(model)
+			Ω(err).Should(BeNil())
+
+			record, err := mq.UnmarshalProtobuf(body)
+			Ω(err).Should(BeNil())
+			Ω(record).Should(Equal(model.Record()))
+		}
+
+		_, err := mq.UnmarshalProtobuf([]byte{0x22, 0x05, 0x08})
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("MessagePack Round Trip", func() {
+		body, err := mq.MessagePackEncoder{}.Encode(encodingModel)
+		Ω(err).Should(BeNil())
+
+		record, err := mq.UnmarshalMessagePack(body)
+		Ω(err).Should(BeNil())
+		Ω(record.Timestamp.Equal(encodingModel.TIMESTAMP)).Should(BeTrue())
+
+		record.Timestamp = encodingModel.TIMESTAMP
+		Ω(record).Should(Equal(encodingModel.Record()))
+	})
+
+	It("Content Type", func() {
+		for _, name := range []string{"json", "protobuf", "msgpack"} {
+			encoder, err := mq.NewEncoder(name, mq.SCHEMA_VERSION_2)
+			Ω(err).Should(BeNil())
+
+			body, _ := encoder.Encode(encodingModel)
+			contentType, unwrapped, err := mq.Unwrap(mq.Wrap(encoder.ContentType(), body))
+			Ω(err).Should(BeNil())
+			Ω(contentType).Should(Equal(encoder.ContentType()))
+			Ω(unwrapped).Should(Equal(body))
+		}
+
+		legacy, _ := mq.Encode(encodingModel, mq.SCHEMA_VERSION_1)
+		contentType, _, _ := mq.Unwrap(mq.Wrap(mq.CONTENT_TYPE_JSON_V1, legacy))
+		Ω(contentType).Should(Equal(mq.CONTENT_TYPE_JSON_V1))
+
+		_, err := mq.NewEncoder("xml", 2)
+		Ω(err).ShouldNot(BeNil())
+	})
+})
+
+func benchmarkEncoder(b *testing.B, encoder mq.Encoder) {
+	b.ReportAllocs()
+
+	var size int
+	for i := 0; i < b.N; i++ {
+		body, _ := encoder.Encode(encodingModel)
+		size = len(body)
+	}
+
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkEncodeJSONv1(b *testing.B)      { benchmarkEncoder(b, mq.JSONEncoder{Version: 1}) }
+func BenchmarkEncodeJSONv2(b *testing.B)      { benchmarkEncoder(b, mq.JSONEncoder{Version: 2}) }
+func BenchmarkEncodeProtobuf(b *testing.B)    { benchmarkEncoder(b, mq.ProtobufEncoder{}) }
+func BenchmarkEncodeMessagePack(b *testing.B) { benchmarkEncoder(b, mq.MessagePackEncoder{}) }
+
+func BenchmarkDecodeJSONv2(b *testing.B) {
+	body, _ := mq.JSONEncoder{Version: 2}.Encode(encodingModel)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var record mq.Record
+		json.Unmarshal(body, &record)
+	}
+}
+
+func BenchmarkDecodeProtobuf(b *testing.B) {
+	body, _ := mq.ProtobufEncoder{}.Encode(encodingModel)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mq.UnmarshalProtobuf(body)
+	}
+}
+
+func BenchmarkDecodeMessagePack(b *testing.B) {
+	body, _ := mq.MessagePackEncoder{}.Encode(encodingModel)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		mq.UnmarshalMessagePack(body)
+	}
+}
@@ -0,0 +1,152 @@
+package signalize
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"biosignal-hamilton-interface/fhir"
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var FHIROutput = Describe("FHIR R4 Output", func() {
+	var start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var device = fhir.NewDevice("0123456789abcdef", "1234")
+
+	var waveforms = func(count int) []mq.QueueModel {
+		var models = []mq.QueueModel{}
+		for i := 0; i < count; i++ {
+			models = append(models, mq.QueueModel{
+				TIMESTAMP:      start.Add(time.Duration(i) * 40 * time.Millisecond),
+				TYPE:           "Waveform",
+				KEY:            "FLOW",
+				VALUE_UNIT:     "L/min",
+				WAVEFORM_VALUE: []float64{float64(i) / 2},
+			})
+		}
+
+		return models
+	}
+
+	It("Mapping Numeric to Observation", func() {
+		var observation = fhir.NumericObservation(mq.QueueModel{
+			TIMESTAMP:     start,
+			TYPE:          "Numeric",
+			KEY:           "f total",
+			IDENTIFIER:    63,
+			MDC_CODE:      151562,
+			MDC_REFID:     "MDC_RESP_RATE",
+			VALUE_UNIT:    "b/min",
+			NUMERIC_VALUE: 14,
+			PATIENT_ID:    "P1",
+		}, "urn:uuid:device")
+
+		Ω(observation.Code.Coding).Should(HaveLen(3))
+		Ω(observation.Code.Coding[0].Code).Should(Equal("9279-1"))
+		Ω(observation.Code.Coding[1]).Should(Equal(fhir.Coding{System: fhir.MDCSystem, Code: "151562", Display: "MDC_RESP_RATE"}))
+		Ω(observation.Code.Coding[2]).Should(Equal(fhir.Coding{System: fhir.LocalSystem, Code: "63", Display: "f total"}))
+		Ω(*observation.ValueQuantity).Should(Equal(fhir.Quantity{Value: 14, Unit: "b/min", System: fhir.UCUMSystem, Code: "/min"}))
+		Ω(observation.Subject.Identifier.Value).Should(Equal("P1"))
+		Ω(observation.Device.Reference).Should(Equal("urn:uuid:device"))
+	})
+
+	It("Mapping Waveform Block to SampledData", func() {
+		var observation = fhir.WaveformObservation(waveforms(5), "urn:uuid:device")
+
+		Ω(observation.EffectivePeriod.Start).Should(Equal("2026-01-02T03:04:05Z"))
+		Ω(observation.ValueSampledData.Period).Should(BeNumerically("~", 40))
+		Ω(observation.ValueSampledData.Origin.Code).Should(Equal("L/min"))
+		Ω(observation.ValueSampledData.Data).Should(Equal("0 0.5 1 1.5 2"))
+	})
+
+	It("Posting Bundles", func() {
+		var bundles = make(chan fhir.Bundle, 10)
+		var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Ω(r.Header.Get("Content-Type")).Should(Equal("application/fhir+json"))
+
+			var bundle fhir.Bundle
+			json.NewDecoder(r.Body).Decode(&bundle)
+			bundles <- bundle
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		var sink = fhir.NewBundleSink(server.URL, "", device)
+		sink.BlockSize = 4
+		for _, model := range waveforms(6) {
+			Ω(sink.Send(model)).Should(BeNil())
+		}
+
+		Ω(sink.Close()).Should(BeNil())
+		Ω(bundles).Should(HaveLen(1))
+
+		var bundle = <-bundles
+		Ω(bundle.Type).Should(Equal("transaction"))
+		Ω(bundle.Entry).Should(HaveLen(3))
+		Ω(bundle.Entry[0].Request.Method).Should(Equal("PUT"))
+		Ω(bundle.Entry[0].FullURL).Should(Equal("urn:uuid:dd0324be-665d-5eac-a185-4fd76126bc5d"))
+	})
+
+	It("UUID Versions", func() {
+		// urn:uuid:xxxxxxxx-xxxx-Vxxx-...
+		Ω(fhir.DeviceUUID("0123456789abcdef")[23:24]).Should(Equal("5"))
+		Ω(fhir.RandomUUID()[23:24]).Should(Equal("4"))
+	})
+
+	It("Name UUID of RFC 4122", func() {
+		Ω(fhir.NameUUID(fhir.NamespaceDNS, "python.org")).Should(Equal("urn:uuid:886313e1-3b8a-5372-9b90-0c9aee199e5d"))
+	})
+
+	It("Limiting Backlog while Server is Down", func() {
+		var sizes = make(chan int, 10)
+		var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var bundle fhir.Bundle
+			json.NewDecoder(r.Body).Decode(&bundle)
+			sizes <- len(bundle.Entry)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		var errs = []error{}
+		var sink = fhir.NewBundleSink(server.URL, "", device)
+		sink.MaxBacklog = 3
+		sink.OnError = func(err error) { errs = append(errs, err) }
+
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 2; j++ {
+				Ω(sink.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "Oxygen", IDENTIFIER: 71, VALUE_UNIT: "%", NUMERIC_VALUE: 40})).Should(BeNil())
+			}
+
+			Ω(sink.Flush()).ShouldNot(BeNil())
+		}
+
+		// 다시 보내는 Observation은 MaxBacklog개를 넘지 않습니다. (Device와 새 Observation 2개 포함)
+		Ω(<-sizes).Should(Equal(3))
+		Ω(<-sizes).Should(Equal(5))
+		Ω(<-sizes).Should(Equal(6))
+		Ω(errs).Should(HaveLen(2))
+		Ω(errs[0].Error()).Should(ContainSubstring("Dropped 1"))
+	})
+
+	It("Writing Bundles to Directory", func() {
+		directory, err := ioutil.TempDir("", "fhir")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(directory)
+
+		// 없는 디렉토리는 만듭니다.
+		directory = filepath.Join(directory, "bundles")
+		var sink = fhir.NewBundleSink("", directory, device)
+		Ω(sink.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "Oxygen", IDENTIFIER: 71, VALUE_UNIT: "%", NUMERIC_VALUE: 40})).Should(BeNil())
+		Ω(sink.Close()).Should(BeNil())
+
+		files, _ := filepath.Glob(filepath.Join(directory, "bundle-*.json"))
+		Ω(files).Should(HaveLen(1))
+	})
+})
@@ -0,0 +1,178 @@
+package signalize
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"time"
+
+	"biosignal-hamilton-interface/hl7"
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 받은 메시지를 넘겨주고, codes 순서대로 ACK를 보내는 MLLP 서버
+func MLLPListener(codes []string, received chan<- string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Ω(err).Should(BeNil())
+
+	go func() {
+		var index = 0
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			var reader = bufio.NewReader(conn)
+			for {
+				message, err := hl7.ReadFrame(reader)
+				if err != nil {
+					conn.Close()
+					break
+				}
+
+				received <- string(message)
+
+				var code = codes[len(codes)-1]
+				if index < len(codes) {
+					code = codes[index]
+				}
+				index++
+
+				var controlID = strings.Split(strings.Split(string(message), "\r")[0], "|")[9]
+				conn.Write(hl7.Frame([]byte("MSH|^~\\&|EHR||||||ACK|1|P|2.5\rMSA|" + code + "|" + controlID + "\r")))
+			}
+		}
+	}()
+
+	return listener
+}
+
+var HL7Output = Describe("HL7 ORU^R01", func() {
+	var model = mq.QueueModel{
+		TIMESTAMP:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		TYPE:          "Numeric",
+		KEY:           "PEEP/CPAP",
+		IDENTIFIER:    68,
+		MDC_CODE:      151976,
+		MDC_REFID:     "MDC_PRESS_AWAY_END_EXP_POS",
+		VALUE_UNIT:    "cmH2O",
+		UDID:          "0123456789abcdef",
+		NUMERIC_VALUE: 5,
+	}
+
+	It("Encoding Observation", func() {
+		var message = string(hl7.EncodeORU(model, hl7.Header{SendingApplication: "HAMILTON"}, hl7.Patient{ID: "P|1", Location: "ICU^01^A"}, "MSG1"))
+		var segments = strings.Split(strings.TrimSuffix(message, "\r"), "\r")
+
+		Ω(segments).Should(HaveLen(5))
+		Ω(segments[0]).Should(HavePrefix("MSH|^~\\&|HAMILTON|"))
+		Ω(segments[0]).Should(ContainSubstring("|ORU^R01^ORU_R01|MSG1|P|2.5"))
+		Ω(segments[1]).Should(HavePrefix("PID|1||P\\F\\1^^^|"))
+		Ω(segments[2]).Should(Equal("PV1|1|I|ICU^01^A"))
+		Ω(segments[4]).Should(HavePrefix("OBX|1|NM|68^PEEP/CPAP^HAMILTON^151976^MDC_PRESS_AWAY_END_EXP_POS^MDC||5|cmH2O|||||F|||20260102030405.000+0000"))
+	})
+
+	It("Parsing ACK", func() {
+		ack, err := hl7.ParseACK([]byte("MSH|^~\\&|EHR||||||ACK|1|P|2.5\rMSA|AE|MSG1|Unknown Patient\r"))
+
+		Ω(err).Should(BeNil())
+		Ω(ack.Code).Should(Equal("AE"))
+		Ω(ack.ControlID).Should(Equal("MSG1"))
+		Ω(ack.Accepted()).Should(BeFalse())
+
+		_, err = hl7.ParseACK([]byte("MSH|^~\\&|EHR\r"))
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("Delivering over MLLP with Retries", func() {
+		var received = make(chan string, 10)
+		var listener = MLLPListener([]string{"AE", "AA"}, received)
+		defer listener.Close()
+
+		var sink = hl7.NewMLLPSink(listener.Addr().String(), hl7.Header{}, hl7.Patient{})
+		sink.RetryDelay = 10 * time.Millisecond
+
+		var errors = make(chan error, 1)
+		sink.OnError = func(err error) { errors <- err }
+
+		Ω(sink.Send(model)).Should(BeNil())
+		Ω(sink.Send(mq.QueueModel{TYPE: "Waveform"})).Should(BeNil())
+		Ω(sink.Close()).Should(BeNil())
+
+		Ω(received).Should(HaveLen(2))
+		Ω(<-received).Should(Equal(<-received))
+		Ω(errors).Should(BeEmpty())
+	})
+
+	It("Giving up after Retries", func() {
+		var received = make(chan string, 10)
+		var listener = MLLPListener([]string{"AE"}, received)
+		defer listener.Close()
+
+		var sink = hl7.NewMLLPSink(listener.Addr().String(), hl7.Header{}, hl7.Patient{})
+		sink.Retries = 1
+		sink.RetryDelay = 10 * time.Millisecond
+
+		var errors = make(chan error, 1)
+		sink.OnError = func(err error) { errors <- err }
+
+		Ω(sink.Send(model)).Should(BeNil())
+		Ω(sink.Close()).Should(BeNil())
+
+		Ω(received).Should(HaveLen(2))
+		Ω(errors).Should(HaveLen(1))
+	})
+
+	It("Not Retrying Rejected Messages", func() {
+		var received = make(chan string, 10)
+		var listener = MLLPListener([]string{"AR"}, received)
+		defer listener.Close()
+
+		var sink = hl7.NewMLLPSink(listener.Addr().String(), hl7.Header{}, hl7.Patient{})
+		sink.RetryDelay = 10 * time.Millisecond
+
+		var errors = make(chan error, 1)
+		sink.OnError = func(err error) { errors <- err }
+
+		Ω(sink.Send(model)).Should(BeNil())
+		Ω(sink.Close()).Should(BeNil())
+
+		Ω(received).Should(HaveLen(1))
+		Ω(errors).Should(HaveLen(1))
+		Ω(<-errors).Should(MatchError(ContainSubstring(hl7.ErrRejected.Error())))
+	})
+
+	It("Control IDs Differ across Restarts", func() {
+		var received = make(chan string, 10)
+		var listener = MLLPListener([]string{"AA"}, received)
+		defer listener.Close()
+
+		for _, session := range []string{"run1", "run2"} {
+			var sink = hl7.NewMLLPSink(listener.Addr().String(), hl7.Header{}, hl7.Patient{})
+			sink.Session = session
+			Ω(sink.Send(model)).Should(BeNil())
+			Ω(sink.Close()).Should(BeNil())
+		}
+
+		var controlID = func(message string) string {
+			return strings.Split(strings.Split(message, "\r")[0], "|")[9]
+		}
+
+		var first, second = controlID(<-received), controlID(<-received)
+		Ω(first).ShouldNot(Equal(second))
+		Ω(first).Should(HaveSuffix("run1000001"))
+		Ω(len(second)).Should(BeNumerically("<=", 20))
+	})
+
+	It("Control ID within 20 Characters", func() {
+		var controlID = hl7.ControlID(strings.Repeat("a", 40), strings.Repeat("b", 16), 1<<31-1)
+		Ω(len(controlID)).Should(BeNumerically("<=", 20))
+		Ω(controlID).Should(HavePrefix("aaaaaabbbbbbbb"))
+
+		Ω(hl7.ControlID("abcdef12", "s", 36)).Should(Equal("abcdefs000010"))
+	})
+})
@@ -1,6 +1,7 @@
 package signalize
 
 import (
+	"errors"
 	"testing"
 
 	"biosignal-hamilton-interface/packet"
@@ -185,3 +186,32 @@ var ResponsePacket = Describe("Outcome Packet Decoder", func() {
 	})
 
 })
+
+var Lookup = Describe("Identifier Lookup", func() {
+	It("Number", func() {
+		identifier, err := packet.LookupIdentifier("43")
+
+		Ω(err).Should(BeNil())
+		Ω(identifier).Should(Equal(byte(43)))
+	})
+
+	It("Name", func() {
+		identifier, err := packet.LookupIdentifier("tidal volume")
+
+		Ω(err).Should(BeNil())
+		Ω(identifier).Should(Equal(byte(43)))
+	})
+
+	It("Ambiguous Name", func() {
+		_, err := packet.LookupIdentifier("Oxygen")
+
+		Ω(errors.Is(err, packet.ErrAmbiguousIdentifier)).Should(BeTrue())
+	})
+
+	It("Numeric Value", func() {
+		pkt, err := packet.ParseResponsePacket([]byte{0x02, 43, 0x20, 0x35, 0x30, 0x30, 0x2E, 0x03, 0x0D})
+
+		Ω(err).Should(BeNil())
+		Ω(pkt.NumericValue()).Should(Equal(float64(500)))
+	})
+})
@@ -0,0 +1,154 @@
+package signalize
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"biosignal-hamilton-interface/live"
+	"biosignal-hamilton-interface/mq"
+	"biosignal-hamilton-interface/storage"
+
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 멈출 때까지 10ms마다 send를 호출합니다.
+func repeat(send func()) (stop func()) {
+	var done = make(chan struct{})
+	go func() {
+		var ticker = time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				send()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+var LiveStreaming = Describe("Live Streaming over WebSocket", func() {
+	var hub *live.Hub
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		hub = live.NewHub()
+		server = httptest.NewServer(hub)
+	})
+
+	AfterEach(func() {
+		hub.Close()
+		server.Close()
+	})
+
+	var connect = func(query string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/live?"+query, nil)
+		Ω(err).Should(BeNil())
+		Eventually(hub.Clients).Should(Equal(1))
+		return conn
+	}
+
+	var receive = func(conn *websocket.Conn) (entry storage.Entry) {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, message, err := conn.ReadMessage()
+		Ω(err).Should(BeNil())
+		Ω(json.Unmarshal(message, &entry)).Should(BeNil())
+		return
+	}
+
+	It("Streaming Filtered Numerics and Waveform Blocks", func() {
+		var conn = connect("udid=abc&type=Numeric,Waveform&block=3")
+		defer conn.Close()
+
+		hub.Send(mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "def", NUMERIC_VALUE: 8})
+		hub.Send(mq.QueueModel{TYPE: "Derived", KEY: "RSBI", UDID: "abc", NUMERIC_VALUE: 80})
+		hub.Send(mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc", NUMERIC_VALUE: 5})
+		for i := 0; i < 3; i++ {
+			hub.Send(mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: []float64{float64(i)}})
+		}
+
+		var numeric = receive(conn)
+		Ω(numeric.Key).Should(Equal("PEEP/CPAP"))
+		Ω(*numeric.NumericValue).Should(Equal(float64(5)))
+
+		var block = receive(conn)
+		Ω(block.Key).Should(Equal("FLOW"))
+		Ω(block.WaveformValue).Should(Equal([]float64{0, 1, 2}))
+		Ω(block.End).ShouldNot(BeNil())
+
+		// 연결한 뒤에 Filter를 바꿀 수 있습니다.
+		Ω(conn.WriteJSON(live.Filter{Types: []string{"Derived"}})).Should(BeNil())
+		var stop = repeat(func() {
+			hub.Send(mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc", NUMERIC_VALUE: 5})
+			hub.Send(mq.QueueModel{TYPE: "Derived", KEY: "RSBI", UDID: "def", NUMERIC_VALUE: 80})
+		})
+		defer stop()
+
+		var derived = receive(conn)
+		for derived.Type == "Numeric" {
+			derived = receive(conn)
+		}
+
+		Ω(derived.Key).Should(Equal("RSBI"))
+	})
+
+	It("Streaming Polled Alarms", func() {
+		var conn = connect("udid=abc&type=Alarm")
+		defer conn.Close()
+
+		// 알람 상태(94 Apnea)는 Alarm 타입으로, 알람 한계(53 High Pressure)는 Numeric 타입으로 만듭니다.
+		var limit = mq.NumericModel(53, 40, "mbar", time.Now())
+		var alarm = mq.NumericModel(94, 1, "", time.Now())
+		Ω(limit.TYPE).Should(Equal("Numeric"))
+		Ω(alarm.TYPE).Should(Equal("Alarm"))
+
+		limit.UDID = "abc"
+		alarm.UDID = "abc"
+		hub.Send(limit)
+		hub.Send(alarm)
+
+		var received = receive(conn)
+		Ω(received.Type).Should(Equal("Alarm"))
+		Ω(received.Key).Should(Equal("Apnea"))
+		Ω(received.Identifier).Should(Equal(94))
+		Ω(*received.NumericValue).Should(Equal(float64(1)))
+	})
+
+	It("Dropping Messages for Slow Clients", func() {
+		hub.Buffer = 4
+		var conn = connect("type=Waveform")
+		defer conn.Close()
+
+		// 클라이언트가 읽지 않는 동안 소켓 버퍼와 대기열이 차면 메시지를 버립니다.
+		var samples = make([]float64, 1000)
+		for i := 0; i < 2000 && hub.Dropped() == 0; i++ {
+			hub.Send(mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: samples})
+		}
+
+		Ω(hub.Dropped()).Should(BeNumerically(">", 0))
+
+		// 다시 읽기 시작하면 버린 메시지 수를 알려줍니다.
+		var stop = repeat(func() {
+			hub.Send(mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: samples})
+		})
+		defer stop()
+
+		var dropped = false
+		for i := 0; i < 10000 && !dropped; i++ {
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			_, message, err := conn.ReadMessage()
+			Ω(err).Should(BeNil())
+			dropped = strings.HasPrefix(string(message), `{"type":"Dropped"`)
+		}
+
+		Ω(dropped).Should(BeTrue())
+	})
+})
@@ -0,0 +1,144 @@
+package signalize
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// 자체 서명 인증서와 키를 directory에 PEM으로 씁니다.
+func WriteCertificate(directory string) (certFile string, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).Should(BeNil())
+
+	var template = &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nsqd.local"},
+		DNSNames:              []string{"nsqd.local"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Ω(err).Should(BeNil())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Ω(err).Should(BeNil())
+
+	certFile = filepath.Join(directory, "cert.pem")
+	keyFile = filepath.Join(directory, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return
+}
+
+var NSQProducerConfig = Describe("NSQ Producer Config", func() {
+	It("Plaintext by Default", func() {
+		config, err := mq.ProducerConfig{}.NSQConfig()
+
+		Ω(err).Should(BeNil())
+		Ω(config.TlsV1).Should(BeFalse())
+		Ω(config.Deflate).Should(BeFalse())
+	})
+
+	It("TLS with CA, Client Certificate and Auth Secret", func() {
+		directory, err := ioutil.TempDir("", "nsq")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(directory)
+
+		certFile, keyFile := WriteCertificate(directory)
+		config, err := mq.ProducerConfig{
+			TLS:        true,
+			CAFile:     certFile,
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			ServerName: "nsqd.local",
+			AuthSecret: "secret",
+			Snappy:     true,
+		}.NSQConfig()
+
+		Ω(err).Should(BeNil())
+		Ω(config.TlsV1).Should(BeTrue())
+		Ω(config.TlsConfig.VerifyConnection).ShouldNot(BeNil())
+		Ω(config.TlsConfig.RootCAs).ShouldNot(BeNil())
+		Ω(config.TlsConfig.Certificates).Should(HaveLen(1))
+		Ω(config.AuthSecret).Should(Equal("secret"))
+		Ω(config.Snappy).Should(BeTrue())
+	})
+
+	It("Verifying Server Name Different from Address", func() {
+		directory, err := ioutil.TempDir("", "nsq")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(directory)
+
+		certFile, keyFile := WriteCertificate(directory)
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		Ω(err).Should(BeNil())
+
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+		Ω(err).Should(BeNil())
+		defer listener.Close()
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}
+		}()
+
+		// go-nsq의 upgradeTLS처럼 ServerName을 주소의 호스트로 덮어쓰고 연결합니다.
+		var handshake = func(serverName string) error {
+			config, err := mq.ProducerConfig{TLS: true, CAFile: certFile, ServerName: serverName}.NSQConfig()
+			Ω(err).Should(BeNil())
+
+			var tlsConfig = config.TlsConfig.Clone()
+			tlsConfig.ServerName = "127.0.0.1"
+
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			Ω(err).Should(BeNil())
+			defer conn.Close()
+
+			return tls.Client(conn, tlsConfig).Handshake()
+		}
+
+		Ω(handshake("nsqd.local")).Should(BeNil())
+		Ω(handshake("other.local")).ShouldNot(BeNil())
+	})
+
+	It("Invalid Combinations", func() {
+		var invalid = []mq.ProducerConfig{
+			{Deflate: true, Snappy: true},
+			{TLS: true, CertFile: "cert.pem"},
+			{CAFile: "ca.pem"},
+			{TLS: true, CAFile: "missing.pem"},
+			{Deflate: true, DeflateLevel: 10},
+		}
+
+		for _, config := range invalid {
+			_, err := config.NSQConfig()
+			Ω(err).ShouldNot(BeNil())
+		}
+	})
+})
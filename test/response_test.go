@@ -0,0 +1,223 @@
+package signalize
+
+import (
+	"errors"
+
+	"biosignal-hamilton-interface/packet"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var TypedResponse = Describe("Typed Response Decoder", func() {
+	It("Decoding RERROR", func() {
+		var bytes = []byte{0x02, 0x52, 0x45, 0x52, 0x52, 0x4F, 0x52, 0x03, 0x0D}
+
+		response, err := packet.ParseResponse(bytes)
+
+		Ω(err).Should(BeNil())
+		Ω(response).Should(Equal(packet.ErrorResponse{}))
+	})
+
+	It("Decoding Numeric", func() {
+		var bytes = []byte{0x02, 43, 0x20, 0x35, 0x30, 0x30, 0x2E, 0x03, 0x0D}
+
+		response, err := packet.ParseResponse(bytes)
+
+		Ω(err).Should(BeNil())
+		Ω(response).Should(BeAssignableToTypeOf(packet.NumericResponse{}))
+
+		numeric := response.(packet.NumericResponse)
+		Ω(numeric.Name()).Should(Equal("Tidal Volume"))
+		Ω(numeric.Unit()).Should(Equal("ml"))
+		Ω(numeric.Value()).Should(Equal(float64(500)))
+	})
+
+	It("Decoding Identity", func() {
+		var bytes = []byte{0x02, 0x7C, 0x47, 0x30, 0x32, 0x30, 0x30, 0x03, 0x0D}
+
+		response, err := packet.ParseResponse(bytes)
+
+		Ω(err).Should(BeNil())
+		Ω(response.Type()).Should(Equal(packet.RESP_TYPE_B_FORMAT_1))
+		Ω(response.(packet.IdentityResponse).Text()).Should(Equal("0200"))
+	})
+
+	It("Decoding Online Values 120", func() {
+		var bytes = []byte{0x02, 120, 0x00, 0x00, 0x20, 0x01, 0x20, 0x3F, 0x3F, 0x00, 0x00, 0x03, 0x0D}
+
+		response, err := packet.ParseResponse(bytes)
+
+		Ω(err).Should(BeNil())
+
+		online := response.(packet.OnlineValues120)
+		Ω(online.PPatient.Signed()).Should(Equal(0))
+		Ω(online.POptional.Signed()).Should(Equal(1))
+		Ω(online.Flow.Count()).Should(Equal(4095))
+		Ω(online.Volume.Count()).Should(Equal(0))
+	})
+
+	It("Compatibility Wrapper", func() {
+		var bytes = []byte{0x02, 120, 0x00, 0x00, 0x20, 0x01, 0x20, 0x3F, 0x3F, 0x00, 0x00, 0x03, 0x0D}
+
+		response, _ := packet.ParseResponse(bytes)
+		pkt, err := packet.ParseResponsePacket(bytes)
+
+		Ω(err).Should(BeNil())
+		Ω(pkt).Should(Equal(response.Packet()))
+		Ω(pkt.ToBytes()).Should(Equal(bytes))
+	})
+
+	It("Decoding - Invalid", func() {
+		response, err := packet.ParseResponse([]byte{0x12, 0x34, 0x56, 0x78, 0x90, 0xAB, 0xCD})
+
+		Ω(err).ShouldNot(BeNil())
+		Ω(response).Should(BeNil())
+	})
+})
+
+var Correlation = Describe("Request-aware Response Decoder", func() {
+	It("Decoding Format 3 by Request", func() {
+		var bytes = []byte{0x02, 0x43, 0x39, 0x39, 0x39, 0x39, 0x03, 0x0D}
+
+		response, err := packet.ParseResponseFor(packet.RequestPacket{Identifier: 0x43}, bytes)
+
+		Ω(err).Should(BeNil())
+		Ω(response.Type()).Should(Equal(packet.RESP_TYPE_B_FORMAT_3))
+	})
+
+	It("Decoding I:E Ratio, P max and P mean as Numerics", func() {
+		for _, identifier := range []byte{0x41, 0x42, 0x43} {
+			var bytes = []byte{0x02, identifier, 0x20, 0x31, 0x32, 0x2E, 0x35, 0x03, 0x0D}
+
+			response, err := packet.ParseResponseFor(packet.RequestPacket{Identifier: identifier}, bytes)
+
+			Ω(err).Should(BeNil())
+			Ω(response.Type()).Should(Equal(packet.RESP_TYPE_A))
+			Ω(response.(packet.NumericResponse).Value()).Should(Equal(12.5))
+		}
+	})
+
+	It("Identifier Mismatch", func() {
+		var bytes = []byte{0x02, 43, 0x20, 0x35, 0x30, 0x30, 0x2E, 0x03, 0x0D}
+
+		response, err := packet.ParseResponseFor(packet.RequestPacket{Identifier: 44}, bytes)
+
+		Ω(response).Should(BeNil())
+		Ω(err).Should(BeAssignableToTypeOf(&packet.ProtocolError{}))
+		Ω(err.(*packet.ProtocolError).Stale).Should(BeFalse())
+	})
+
+	It("Unexpected Length", func() {
+		var bytes = []byte{0x02, 120, 0x00, 0x00, 0x20, 0x03, 0x0D}
+
+		_, err := packet.ParseResponseFor(packet.RequestPacket{Identifier: 120}, bytes)
+
+		Ω(err).Should(BeAssignableToTypeOf(&packet.ProtocolError{}))
+	})
+
+	It("Stale Reply", func() {
+		var correlator = packet.Correlator{}
+		correlator.Send(packet.RequestPacket{Identifier: 120})
+		correlator.Send(packet.RequestPacket{Identifier: 43})
+
+		response, err := correlator.Receive([]byte{0x02, 120, 0x00, 0x00, 0x20, 0x01, 0x20, 0x3F, 0x3F, 0x00, 0x00, 0x03, 0x0D})
+
+		Ω(response).Should(BeNil())
+		Ω(err.(*packet.ProtocolError).Stale).Should(BeTrue())
+
+		response, err = correlator.Receive([]byte{0x02, 43, 0x20, 0x35, 0x30, 0x30, 0x2E, 0x03, 0x0D})
+
+		Ω(err).Should(BeNil())
+		Ω(response.(packet.NumericResponse).Identifier).Should(Equal(byte(43)))
+	})
+
+	var stale = []byte{0x02, 120, 0x00, 0x00, 0x20, 0x01, 0x20, 0x3F, 0x3F, 0x00, 0x00, 0x03, 0x0D}
+	var current = []byte{0x02, 43, 0x20, 0x35, 0x30, 0x30, 0x2E, 0x03, 0x0D}
+
+	It("Reading Again after Stale Reply", func() {
+		var correlator = packet.Correlator{}
+		var reads = [][]byte{stale, current, current}
+		var read = func() ([]byte, error) {
+			var raw = reads[0]
+			reads = reads[1:]
+			return raw, nil
+		}
+
+		correlator.Send(packet.RequestPacket{Identifier: 120})
+		correlator.Send(packet.RequestPacket{Identifier: 43})
+		response, err := correlator.ReceiveFrom(read)
+		Ω(err).Should(BeNil())
+		Ω(response.(packet.NumericResponse).Identifier).Should(Equal(byte(43)))
+
+		// 다음 요청의 응답도 밀리지 않습니다.
+		correlator.Send(packet.RequestPacket{Identifier: 43})
+		response, err = correlator.ReceiveFrom(read)
+		Ω(err).Should(BeNil())
+		Ω(response.(packet.NumericResponse).Identifier).Should(Equal(byte(43)))
+		Ω(reads).Should(BeEmpty())
+	})
+
+	It("Stale Reply Read Together with Current Reply", func() {
+		var correlator = packet.Correlator{}
+		correlator.Send(packet.RequestPacket{Identifier: 120})
+		correlator.Send(packet.RequestPacket{Identifier: 43})
+
+		response, err := correlator.Receive(append(append([]byte{}, stale...), current...))
+		Ω(err).Should(BeNil())
+		Ω(response.(packet.NumericResponse).Identifier).Should(Equal(byte(43)))
+	})
+
+	It("Giving Up on Stale Replies", func() {
+		var correlator = packet.Correlator{}
+		var count = 0
+		correlator.Send(packet.RequestPacket{Identifier: 120})
+		correlator.Send(packet.RequestPacket{Identifier: 43})
+
+		_, err := correlator.ReceiveFrom(func() ([]byte, error) {
+			count++
+			return stale, nil
+		})
+		Ω(errors.Is(err, packet.ErrStaleReply)).Should(BeTrue())
+		Ω(count).Should(Equal(packet.MaxStaleReplies + 1))
+	})
+})
+
+var ErrorTaxonomy = Describe("Packet Errors", func() {
+	It("Request Framing", func() {
+		_, err := packet.ParseRequestPacket([]byte{0x01, 0x40, 0x03, 0x0D})
+		Ω(errors.Is(err, packet.ErrBadSTX)).Should(BeTrue())
+
+		_, err = packet.ParseRequestPacket([]byte{0x02, 0x40, 0x03, 0x0A})
+		Ω(errors.Is(err, packet.ErrBadTerminator)).Should(BeTrue())
+
+		_, err = packet.ParseRequestPacket([]byte{0x02, 0x03, 0x0D})
+		Ω(errors.Is(err, packet.ErrShortFrame)).Should(BeTrue())
+	})
+
+	It("Offset of Unknown Identifier", func() {
+		_, err := packet.ParseResponse([]byte{0x02, 0x01, 0x30, 0x30, 0x30, 0x30, 0x30, 0x03, 0x0D})
+
+		var frameError *packet.FrameError
+		Ω(errors.As(err, &frameError)).Should(BeTrue())
+		Ω(frameError.Err).Should(Equal(packet.ErrUnknownIdentifier))
+		Ω(frameError.Offset).Should(Equal(1))
+	})
+
+	It("Device RERROR", func() {
+		var bytes = []byte{0x02, 0x52, 0x45, 0x52, 0x52, 0x4F, 0x52, 0x03, 0x0D}
+
+		response, err := packet.ParseResponseFor(packet.RequestPacket{Identifier: 43}, bytes)
+
+		Ω(response).Should(Equal(packet.ErrorResponse{}))
+		Ω(errors.Is(err, packet.ErrDeviceRERROR)).Should(BeTrue())
+	})
+
+	It("Wrapped in ProtocolError", func() {
+		_, err := packet.ParseResponseFor(packet.RequestPacket{Identifier: 43}, []byte{0x02, 43, 0x20, 0x35, 0x30, 0x30, 0x2E, 0x03})
+
+		var protocolError *packet.ProtocolError
+		Ω(errors.As(err, &protocolError)).Should(BeTrue())
+		Ω(errors.Is(err, packet.ErrBadTerminator)).Should(BeTrue())
+	})
+})
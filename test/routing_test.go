@@ -0,0 +1,47 @@
+package signalize
+
+import (
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var TopicRouting = Describe("Topic Routing", func() {
+	var waveform = mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", UDID: "abc123"}
+	var numeric = mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP", IDENTIFIER: 68, UDID: "abc123"}
+
+	It("Parsing Rules", func() {
+		route, err := mq.ParseRoute("type=Numeric,key=PEEP/CPAP=>numerics, alerts")
+		Ω(err).Should(BeNil())
+		Ω(route).Should(Equal(mq.Route{Type: "Numeric", Key: "PEEP/CPAP", Topics: []string{"numerics", "alerts"}}))
+
+		route, err = mq.ParseRoute("*=>everything")
+		Ω(err).Should(BeNil())
+		Ω(route.Match(waveform)).Should(BeTrue())
+
+		for _, rule := range []string{"type=Numeric", "port=COM1=>a", "type=Numeric=>", "*=>bad topic!", "*=>{udid}{udid}"} {
+			_, err = mq.ParseRoute(rule)
+			Ω(err).ShouldNot(BeNil(), rule)
+		}
+	})
+
+	It("Routing by Type, Key and Device with Templates", func() {
+		var router = mq.Router{Default: []string{"Biosignal"}}
+		for _, rule := range []string{
+			"type=waveform=>hamilton.{udid}.{type}",
+			"type=Numeric=>hamilton.numeric",
+			"key=PEEP/CPAP=>hamilton.{key}.{identifier},hamilton.numeric",
+			"udid=other=>other",
+		} {
+			route, err := mq.ParseRoute(rule)
+			Ω(err).Should(BeNil())
+			router.Routes = append(router.Routes, route)
+		}
+
+		Ω(router.Topics(waveform)).Should(Equal([]string{"hamilton.abc123.waveform"}))
+		Ω(router.Topics(numeric)).Should(Equal([]string{"hamilton.numeric", "hamilton.PEEP_CPAP.68"}))
+		Ω(router.Topics(mq.QueueModel{TYPE: "Breath", UDID: "abc123"})).Should(Equal([]string{"Biosignal"}))
+		Ω(router.Topics(mq.QueueModel{TYPE: "Breath", UDID: "other"})).Should(Equal([]string{"other"}))
+	})
+})
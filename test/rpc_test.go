@@ -0,0 +1,151 @@
+package signalize
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"biosignal-hamilton-interface/mq"
+	"biosignal-hamilton-interface/rpc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var GRPCAPI = Describe("gRPC API over Unix Socket", func() {
+	var directory string
+	var server *rpc.Server
+	var grpcServer *grpc.Server
+	var client *rpc.Client
+	var start = time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		var err error
+		directory, err = os.MkdirTemp("", "signalize-rpc")
+		Ω(err).Should(BeNil())
+
+		// 이전에 남은 소켓 파일이 있어도 다시 열 수 있습니다.
+		var address = "unix://" + filepath.Join(directory, "signalize.sock")
+		for i := 0; i < 2; i++ {
+			listener, err := rpc.Listen(address)
+			Ω(err).Should(BeNil())
+			if i == 0 {
+				listener.(interface{ SetUnlinkOnClose(bool) }).SetUnlinkOnClose(false)
+				listener.Close()
+				continue
+			}
+
+			server = rpc.NewServer()
+			grpcServer = server.Register()
+			go grpcServer.Serve(listener)
+		}
+
+		client, err = rpc.Dial(address)
+		Ω(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		server.Close()
+		grpcServer.Stop()
+		os.RemoveAll(directory)
+	})
+
+	It("Listing Devices and Latest Numerics", func() {
+		server.SetNumber("abc", "H-1234")
+		server.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc", PORT: "ttyUSB0", NUMERIC_VALUE: 5})
+		server.Send(mq.QueueModel{TIMESTAMP: start.Add(time.Second), TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc", PORT: "ttyUSB0", NUMERIC_VALUE: 6})
+		server.Send(mq.QueueModel{TIMESTAMP: start.Add(time.Second), TYPE: "Derived", KEY: "RSBI", UDID: "abc", NUMERIC_VALUE: 80})
+		server.Send(mq.QueueModel{TIMESTAMP: start.Add(2 * time.Second), TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: []float64{1}})
+		server.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "Ppeak", UDID: "def", NUMERIC_VALUE: 20})
+
+		var ctx = context.Background()
+		devices, err := client.ListDevices(ctx)
+		Ω(err).Should(BeNil())
+		Ω(devices).Should(HaveLen(2))
+		Ω(devices[0].UDID).Should(Equal("abc"))
+		Ω(devices[0].Number).Should(Equal("H-1234"))
+		Ω(devices[0].Port).Should(Equal("ttyUSB0"))
+		Ω(devices[0].FirstSeen.Equal(start)).Should(BeTrue())
+		Ω(devices[0].LastSeen.Equal(start.Add(2 * time.Second))).Should(BeTrue())
+		Ω(devices[0].Channels).Should(Equal([]string{"FLOW"}))
+
+		device, err := client.GetDevice(ctx, "def")
+		Ω(err).Should(BeNil())
+		Ω(device.UDID).Should(Equal("def"))
+
+		_, err = client.GetDevice(ctx, "xyz")
+		Ω(status.Code(err)).Should(Equal(codes.NotFound))
+
+		records, err := client.LatestNumerics(ctx, "abc")
+		Ω(err).Should(BeNil())
+		Ω(records).Should(HaveLen(2))
+		Ω(records[0].Key).Should(Equal("PEEP/CPAP"))
+		Ω(*records[0].NumericValue).Should(Equal(float64(6)))
+		Ω(records[1].Key).Should(Equal("RSBI"))
+
+		records, err = client.LatestNumerics(ctx, "abc", "rsbi")
+		Ω(err).Should(BeNil())
+		Ω(records).Should(HaveLen(1))
+	})
+
+	It("Streaming Waveform Blocks and Events", func() {
+		var ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+
+		waveforms, err := client.StreamWaveforms(ctx, rpc.StreamRequest{UDID: "abc", Keys: []string{"FLOW"}, Block: 3})
+		Ω(err).Should(BeNil())
+		events, err := client.StreamEvents(ctx, rpc.StreamRequest{})
+		Ω(err).Should(BeNil())
+		Eventually(server.Streams).Should(Equal(2))
+
+		server.Send(mq.QueueModel{TYPE: "Waveform", KEY: "PAW", UDID: "abc", WAVEFORM_VALUE: []float64{9}})
+		server.Send(mq.QueueModel{TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc", NUMERIC_VALUE: 5})
+		server.Send(mq.QueueModel{TYPE: "Event", KEY: "ASYNCHRONY", UDID: "abc", NUMERIC_VALUE: 1})
+		server.Send(mq.QueueModel{TYPE: "Alarm", KEY: "Apnea", IDENTIFIER: 94, UDID: "abc", NUMERIC_VALUE: 1})
+		for i := 0; i < 3; i++ {
+			server.Send(mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: []float64{float64(i)}})
+		}
+
+		block, err := waveforms.Recv()
+		Ω(err).Should(BeNil())
+		Ω(block.Key).Should(Equal("FLOW"))
+		Ω(block.WaveformValue).Should(Equal([]float64{0, 1, 2}))
+
+		event, err := events.Recv()
+		Ω(err).Should(BeNil())
+		Ω(event.Type).Should(Equal("Event"))
+		Ω(event.Key).Should(Equal("ASYNCHRONY"))
+
+		alarm, err := events.Recv()
+		Ω(err).Should(BeNil())
+		Ω(alarm.Type).Should(Equal("Alarm"))
+		Ω(alarm.Identifier).Should(Equal(94))
+
+		// 클라이언트가 취소하면 스트림이 정리됩니다.
+		cancel()
+		Eventually(server.Streams).Should(Equal(0))
+	})
+
+	It("Dropping Messages for Slow Streams", func() {
+		server.Buffer = 1
+		var ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+
+		_, err := client.StreamEvents(ctx, rpc.StreamRequest{Types: []string{"Waveform"}})
+		Ω(err).Should(BeNil())
+		Eventually(server.Streams).Should(Equal(1))
+
+		// 읽지 않는 동안 대기열이 차면 Send가 막히지 않고 메시지를 버립니다.
+		var samples = make([]float64, 1000)
+		for i := 0; i < 5000 && server.Dropped() == 0; i++ {
+			Ω(server.Send(mq.QueueModel{TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: samples})).Should(BeNil())
+		}
+
+		Ω(server.Dropped()).Should(BeNumerically(">", 0))
+	})
+})
@@ -0,0 +1,98 @@
+package signalize
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var MessageSchema = Describe("Message Schema", func() {
+	var model = mq.QueueModel{
+		TIMESTAMP:      time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		TYPE:           "Waveform",
+		KEY:            "FLOW",
+		PORT:           "/dev/ttyUSB0",
+		VALUE_UNIT:     "L/min",
+		UDID:           "udid",
+		WAVEFORM_VALUE: []float64{1.5},
+	}
+
+	It("Version 1 is Unchanged", func() {
+		body, err := mq.Encode(model, mq.SCHEMA_VERSION_1)
+		Ω(err).Should(BeNil())
+
+		var decoded map[string]interface{}
+		Ω(json.Unmarshal(body, &decoded)).Should(Succeed())
+		Ω(decoded["TIMESTAMP"]).Should(Equal("2026-01-02T03:04:05Z"))
+		Ω(decoded).ShouldNot(HaveKey("SEQUENCE"))
+		Ω(decoded).Should(HaveKey("NUMERIC_VALUE"))
+	})
+
+	It("Version 2 with Nanoseconds and Sequence", func() {
+		var sequencer = mq.NewSequencer()
+		var other = model
+		other.UDID = "other"
+
+		sequencer.Stamp(&model)
+		sequencer.Stamp(&other)
+		sequencer.Stamp(&model)
+		Ω(model.SEQUENCE).Should(Equal(uint64(2)))
+		Ω(other.SEQUENCE).Should(Equal(uint64(1)))
+		Ω(model.SESSION).Should(Equal(sequencer.Session))
+
+		body, err := mq.Encode(model, mq.SCHEMA_VERSION_2)
+		Ω(err).Should(BeNil())
+
+		var decoded map[string]interface{}
+		Ω(json.Unmarshal(body, &decoded)).Should(Succeed())
+		Ω(decoded["schema_version"]).Should(BeNumerically("==", 2))
+		Ω(decoded["timestamp"]).Should(Equal("2026-01-02T03:04:05.123456789Z"))
+		Ω(decoded["sequence"]).Should(BeNumerically("==", 2))
+		Ω(decoded["port"]).Should(Equal("/dev/ttyUSB0"))
+		Ω(decoded).ShouldNot(HaveKey("numeric_value"))
+
+		_, err = mq.Encode(model, 3)
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("Trend without Valid Values Has No Numeric Value", func() {
+		var trend = mq.QueueModel{TYPE: "Trend", KEY: "PEEP/CPAP", TREND: &mq.TrendStatistics{INTERVAL: "1m0s", INVALID: 3}}
+		Ω(trend.Record().NumericValue).Should(BeNil())
+
+		trend.TREND.COUNT = 1
+		Ω(trend.Record().NumericValue).ShouldNot(BeNil())
+	})
+
+	It("Published JSON Schema Matches Go Types", func() {
+		for _, version := range []int{mq.SCHEMA_VERSION_1, mq.SCHEMA_VERSION_2} {
+			schema, err := mq.JSONSchema(version)
+			Ω(err).Should(BeNil())
+
+			published, err := ioutil.ReadFile(fmt.Sprintf("../schema/record.v%d.json", version))
+			Ω(err).Should(BeNil())
+			Ω(strings.TrimSpace(string(published))).Should(Equal(string(schema)))
+		}
+	})
+
+	It("Every Version 2 Field is Described", func() {
+		schema, _ := mq.JSONSchema(mq.SCHEMA_VERSION_2)
+
+		var decoded struct {
+			Properties map[string]map[string]interface{}
+			Required   []string
+		}
+		Ω(json.Unmarshal(schema, &decoded)).Should(Succeed())
+		Ω(decoded.Required).Should(ContainElement("schema_version"))
+
+		for name, property := range decoded.Properties {
+			Ω(property).Should(HaveKey("description"), name)
+		}
+	})
+})
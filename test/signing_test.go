@@ -0,0 +1,82 @@
+package signalize
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"biosignal-hamilton-interface/mq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var MessageSigning = Describe("Message Signing", func() {
+	var message = mq.Wrap(mq.CONTENT_TYPE_PROTOBUF, []byte{0x08, 0x02})
+
+	It("HMAC-SHA256", func() {
+		var secret = []byte("0123456789abcdef0123456789abcdef")
+		var signed = mq.Sign(mq.HMACSigner{ID: "bedside-1", Key: secret}, message)
+		var ring = mq.KeyRing{"bedside-1": {Algorithm: mq.SIGN_HMAC_SHA256, Key: secret}}
+
+		payload, keyID, err := ring.Verify(signed)
+		Ω(err).Should(BeNil())
+		Ω(keyID).Should(Equal("bedside-1"))
+		Ω(payload).Should(Equal(message))
+
+		contentType, _, err := mq.Unwrap(payload)
+		Ω(err).Should(BeNil())
+		Ω(contentType).Should(Equal(mq.CONTENT_TYPE_PROTOBUF))
+
+		var tampered = append([]byte{}, signed...)
+		tampered[len(tampered)-1] ^= 0x01
+		_, _, err = ring.Verify(tampered)
+		Ω(errors.Is(err, mq.ErrBadSignature)).Should(BeTrue())
+
+		_, _, err = mq.KeyRing{}.Verify(signed)
+		Ω(errors.Is(err, mq.ErrUnknownKey)).Should(BeTrue())
+
+		_, _, err = ring.Verify(message)
+		Ω(errors.Is(err, mq.ErrUnsigned)).Should(BeTrue())
+
+		_, _, err = mq.Unwrap(signed)
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("Ed25519 Keys from PEM", func() {
+		directory, err := ioutil.TempDir("", "signing")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(directory)
+
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Ω(err).Should(BeNil())
+
+		privateDER, _ := x509.MarshalPKCS8PrivateKey(private)
+		publicDER, _ := x509.MarshalPKIXPublicKey(public)
+		ioutil.WriteFile(filepath.Join(directory, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+		ioutil.WriteFile(filepath.Join(directory, "pub.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+
+		signer, err := mq.LoadSigner(mq.SIGN_ED25519, "vent-2026", filepath.Join(directory, "key.pem"))
+		Ω(err).Should(BeNil())
+
+		key, err := mq.LoadVerifyKey(mq.SIGN_ED25519, filepath.Join(directory, "pub.pem"))
+		Ω(err).Should(BeNil())
+
+		payload, keyID, err := mq.KeyRing{"vent-2026": key}.Verify(mq.Sign(signer, message))
+		Ω(err).Should(BeNil())
+		Ω(keyID).Should(Equal("vent-2026"))
+		Ω(payload).Should(Equal(message))
+
+		// 같은 Key ID라도 알고리즘이 다르면 검증하지 않습니다.
+		_, _, err = mq.KeyRing{"vent-2026": {Algorithm: mq.SIGN_HMAC_SHA256, Key: public}}.Verify(mq.Sign(signer, message))
+		Ω(errors.Is(err, mq.ErrUnknownKey)).Should(BeTrue())
+
+		_, err = mq.LoadSigner(mq.SIGN_ED25519, "bad:id", filepath.Join(directory, "key.pem"))
+		Ω(err).ShouldNot(BeNil())
+	})
+})
@@ -0,0 +1,177 @@
+package signalize
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"biosignal-hamilton-interface/mq"
+	"biosignal-hamilton-interface/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var LocalStorage = Describe("Local Storage", func() {
+	var directory string
+	var store *storage.Store
+	var start = time.Date(2026, 10, 19, 9, 59, 59, 0, time.UTC)
+
+	BeforeEach(func() {
+		var err error
+		directory, err = ioutil.TempDir("", "storage")
+		Ω(err).Should(BeNil())
+
+		store, err = storage.Open(directory)
+		Ω(err).Should(BeNil())
+		store.BlockSize = 3
+	})
+
+	AfterEach(func() {
+		store.Close()
+		os.RemoveAll(directory)
+	})
+
+	var scan = func(query storage.Query) []storage.Entry {
+		var entries = []storage.Entry{}
+		Ω(store.Scan(query, func(entry storage.Entry) error {
+			entries = append(entries, entry)
+			return nil
+		})).Should(BeNil())
+
+		return entries
+	}
+
+	It("Storing Numerics and Waveform Blocks per Device", func() {
+		for i := 0; i < 4; i++ {
+			var timestamp = start.Add(time.Duration(i) * 500 * time.Millisecond)
+			Ω(store.Send(mq.QueueModel{TIMESTAMP: timestamp, TYPE: "Waveform", KEY: "FLOW", UDID: "abc", VALUE_UNIT: "l/min", WAVEFORM_VALUE: []float64{float64(i)}})).Should(BeNil())
+			Ω(store.Send(mq.QueueModel{TIMESTAMP: timestamp, TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc", NUMERIC_VALUE: 5})).Should(BeNil())
+		}
+
+		Ω(store.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "def", NUMERIC_VALUE: 8})).Should(BeNil())
+		Ω(store.Flush()).Should(BeNil())
+
+		// 10시 전후로 세그먼트가 나뉩니다.
+		segments, _ := filepath.Glob(filepath.Join(directory, "abc", "*.jsonl"))
+		Ω(segments).Should(HaveLen(2))
+
+		devices, err := store.Devices()
+		Ω(err).Should(BeNil())
+		Ω(devices).Should(Equal([]string{"abc", "def"}))
+
+		var waveforms = scan(storage.Query{UDID: "abc", Types: []string{"Waveform"}})
+		Ω(waveforms).Should(HaveLen(2))
+		Ω(waveforms[0].WaveformValue).Should(Equal([]float64{0, 1, 2}))
+		Ω(*waveforms[0].End).Should(Equal(start.Add(time.Second)))
+
+		times, values := waveforms[0].Points()
+		Ω(times[1]).Should(Equal(start.Add(500 * time.Millisecond)))
+		Ω(values).Should(Equal([]float64{0, 1, 2}))
+
+		var numerics = scan(storage.Query{From: start.Add(time.Second), Types: []string{"Numeric"}})
+		Ω(numerics).Should(HaveLen(2))
+		Ω(numerics[0].Timestamp).Should(Equal(start.Add(time.Second)))
+	})
+
+	It("Exporting CSV and JSON", func() {
+		store.BlockSize = 2
+		store.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: []float64{1.5}})
+		store.Send(mq.QueueModel{TIMESTAMP: start.Add(time.Second), TYPE: "Waveform", KEY: "FLOW", UDID: "abc", WAVEFORM_VALUE: []float64{2.5}})
+
+		var buffer = bytes.Buffer{}
+		Ω(store.Export(&buffer, "csv", storage.Query{})).Should(BeNil())
+		var lines = strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		Ω(lines).Should(HaveLen(3))
+		Ω(lines[0]).Should(Equal("timestamp,udid,session,sequence,type,key,unit,value,quality"))
+		Ω(lines[2]).Should(HavePrefix("2026-10-19T10:00:00Z,abc,"))
+		Ω(lines[2]).Should(HaveSuffix(",Waveform,FLOW,,2.5,"))
+
+		buffer.Reset()
+		Ω(store.Export(&buffer, "json", storage.Query{Types: []string{"Numeric"}})).Should(BeNil())
+		Ω(buffer.String()).Should(Equal("[]\n"))
+
+		Ω(store.Export(&buffer, "xml", storage.Query{})).ShouldNot(BeNil())
+	})
+
+	It("Failing Records Request on Corrupt Segment", func() {
+		var server = httptest.NewServer(store)
+		defer server.Close()
+
+		for i := 0; i < 2; i++ {
+			Ω(store.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: fmt.Sprintf("abc%d", i)})).Should(BeNil())
+		}
+
+		Ω(store.Flush()).Should(BeNil())
+
+		var corrupt = func(udid string, flag int) {
+			segments, _ := filepath.Glob(filepath.Join(directory, udid, "*.jsonl"))
+			file, _ := os.OpenFile(segments[0], flag|os.O_WRONLY, 0644)
+			file.WriteString("not json\n")
+			file.Close()
+		}
+
+		// 아무것도 보내기 전이면 500으로 응답합니다.
+		corrupt("abc0", os.O_TRUNC)
+		response, err := http.Get(server.URL + "?udid=abc0")
+		Ω(err).Should(BeNil())
+		response.Body.Close()
+		Ω(response.StatusCode).Should(Equal(http.StatusInternalServerError))
+
+		// 보내는 도중이면 연결을 끊어 잘린 응답임을 알립니다. (응답 헤더를 보내기 전이면 요청이 실패합니다)
+		corrupt("abc1", os.O_APPEND)
+		response, err = http.Get(server.URL + "?udid=abc1")
+		if err == nil {
+			_, err = ioutil.ReadAll(response.Body)
+			response.Body.Close()
+		}
+
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	It("Opening Read Only", func() {
+		var missing = filepath.Join(directory, "missing")
+		_, err := storage.OpenReadOnly(missing)
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+
+		_, err = os.Stat(missing)
+		Ω(os.IsNotExist(err)).Should(BeTrue())
+
+		Ω(store.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc"})).Should(BeNil())
+		Ω(store.Flush()).Should(BeNil())
+
+		reader, err := storage.OpenReadOnly(directory)
+		Ω(err).Should(BeNil())
+		defer reader.Close()
+
+		Ω(reader.Send(mq.QueueModel{TIMESTAMP: start, TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc"})).Should(Equal(storage.ErrReadOnly))
+		devices, err := reader.Devices()
+		Ω(err).Should(BeNil())
+		Ω(devices).Should(Equal([]string{"abc"}))
+	})
+
+	It("Retention and Truncated Lines", func() {
+		for _, age := range []time.Duration{5 * time.Hour, 4 * time.Hour, time.Hour, 0} {
+			Ω(store.Send(mq.QueueModel{TIMESTAMP: start.Add(-age), TYPE: "Numeric", KEY: "PEEP/CPAP", UDID: "abc"})).Should(BeNil())
+		}
+
+		store.Retention = 2 * time.Hour
+		Ω(store.Prune(start)).Should(BeNil())
+
+		segments, _ := filepath.Glob(filepath.Join(directory, "abc", "*.jsonl"))
+		Ω(segments).Should(HaveLen(2))
+
+		// 쓰는 도중에 죽어서 잘린 줄은 버립니다.
+		file, _ := os.OpenFile(segments[1], os.O_APPEND|os.O_WRONLY, 0644)
+		file.WriteString(`{"schema_version":2,"type":"Num`)
+		file.Close()
+
+		Ω(scan(storage.Query{})).Should(HaveLen(2))
+	})
+})
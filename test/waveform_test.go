@@ -0,0 +1,134 @@
+package signalize
+
+import (
+	"testing"
+
+	"biosignal-hamilton-interface/packet"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var WaveformDecoder = Describe("Waveform Decoder", func() {
+	It("Same Result as Bit Array", func() {
+		for high := 0; high < 0x40; high++ {
+			for low := 0; low < 0x40; low++ {
+				var bits = packet.ConvertBitWaveform(byte(high), byte(low))
+
+				Ω(int(packet.Decode12Bit(byte(high), byte(low)))).Should(Equal(packet.BitArrayToInteger(bits)))
+			}
+		}
+	})
+
+	It("Signed Value", func() {
+		Ω(packet.DecodeSigned12Bit(0x20, 0x00)).Should(Equal(0))
+		Ω(packet.DecodeSigned12Bit(0x00, 0x00)).Should(Equal(-2048))
+		Ω(packet.DecodeSigned12Bit(0x3F, 0x3F)).Should(Equal(2047))
+	})
+
+	It("Zero Allocation", func() {
+		var allocs = testing.AllocsPerRun(100, func() {
+			packet.DecodeSigned12Bit(0x39, 0x39)
+		})
+
+		Ω(allocs).Should(BeZero())
+	})
+})
+
+var PhysicalUnit = Describe("Waveform Calibration", func() {
+	It("Physical Value", func() {
+		var online = packet.OnlineValues120{
+			PPatient:  packet.WaveformValue{High: 0x21, Low: 0x00},
+			POptional: packet.WaveformValue{High: 0x20, Low: 0x00},
+			Flow:      packet.WaveformValue{High: 0x1F, Low: 0x00},
+			Volume:    packet.WaveformValue{High: 0x2F, Low: 0x20},
+		}
+
+		var samples = online.Samples()
+
+		Ω(samples[0].Key).Should(Equal("P_PATIENT"))
+		Ω(samples[0].Unit).Should(Equal("cmH2O"))
+		Ω(samples[0].Value()).Should(BeNumerically("~", 6.4, 1e-9))
+		Ω(samples[1].Value()).Should(BeNumerically("~", 0, 1e-9))
+		Ω(samples[2].Value()).Should(BeNumerically("~", -6.4, 1e-9))
+		Ω(samples[3].Unit).Should(Equal("mL"))
+		Ω(samples[3].Count).Should(Equal(uint16(3040)))
+	})
+
+	It("Override", func() {
+		var original = packet.Calibrations["FLOW"]
+		defer func() { packet.Calibrations["FLOW"] = original }()
+
+		Ω(packet.SetCalibration("flow", "0.5:2000")).Should(Succeed())
+		Ω(packet.Calibrations["FLOW"].Physical(2010)).Should(BeNumerically("~", 5, 1e-9))
+		Ω(packet.SetCalibration("FLOW", "0.5")).ShouldNot(Succeed())
+		Ω(packet.SetCalibration("UNKNOWN", "0.5:2000")).ShouldNot(Succeed())
+	})
+})
+
+var MDCNomenclature = Describe("IEEE 11073 Nomenclature", func() {
+	It("Every Waveform Channel is Mapped or Listed as Unmapped", func() {
+		for key := range packet.Calibrations {
+			_, mapped := packet.ChannelNomenclatures[key]
+			_, unmapped := packet.UnmappedChannels[key]
+			Ω(mapped != unmapped).Should(BeTrue(), "channel %s", key)
+		}
+
+		for key, nomenclature := range packet.ChannelNomenclatures {
+			Ω(nomenclature.Code).ShouldNot(BeZero(), "channel %s", key)
+			Ω(nomenclature.RefID).Should(HavePrefix("MDC_"))
+		}
+
+		for identifier, nomenclature := range packet.Nomenclatures {
+			Ω(packet.TypeIntString).Should(HaveKey(identifier))
+			Ω(nomenclature.Code).ShouldNot(BeZero(), "identifier %d", identifier)
+			Ω(nomenclature.RefID).Should(HavePrefix("MDC_"))
+		}
+	})
+
+	It("Every Numeric Identifier is Mapped or Listed as Unmapped", func() {
+		for identifier := range packet.TypeIntString {
+			reason, unmapped := packet.UnmappedIdentifiers[identifier]
+			if _, numeric := packet.ExpectedFormats(packet.RequestPacket{Identifier: byte(identifier)})[packet.RESP_TYPE_A]; !numeric {
+				Ω(unmapped).Should(BeFalse(), "identifier %d", identifier)
+				continue
+			}
+
+			_, mapped := packet.Nomenclatures[identifier]
+			Ω(mapped != unmapped).Should(BeTrue(), "identifier %d", identifier)
+			if unmapped {
+				Ω(reason).ShouldNot(BeEmpty())
+			}
+		}
+	})
+
+	It("Override", func() {
+		defer delete(packet.ChannelNomenclatures, "P_OPTIONAL")
+		var original = packet.Nomenclatures[68]
+		defer func() { packet.Nomenclatures[68] = original }()
+
+		Ω(packet.SetNomenclature("68", "151976:MDC_PRESS_AWAY_END_EXP_POS")).Should(Succeed())
+		Ω(packet.SetNomenclature("p_optional", "0:MDC_PRESS_AWAY_AUX")).Should(Succeed())
+		Ω(packet.ChannelNomenclatures["P_OPTIONAL"].RefID).Should(Equal("MDC_PRESS_AWAY_AUX"))
+
+		Ω(packet.SetNomenclature("68", "MDC_PRESS_AWAY_END_EXP_POS")).ShouldNot(Succeed())
+		Ω(packet.SetNomenclature("9999", "1:MDC_X")).ShouldNot(Succeed())
+		Ω(packet.SetNomenclature("UNKNOWN", "1:MDC_X")).ShouldNot(Succeed())
+	})
+})
+
+var decoded int
+
+func BenchmarkDecode12Bit(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		decoded = packet.DecodeSigned12Bit(byte(i>>6), byte(i))
+	}
+}
+
+func BenchmarkConvertBitWaveform(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		decoded = packet.BitArrayToInteger(packet.ConvertBitWaveform(byte(i>>6), byte(i))) - 2048
+	}
+}